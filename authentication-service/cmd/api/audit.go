@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// audit records a security event for the request. A failed write is logged
+// rather than failing the request, so an audit outage cannot lock users out.
+func (app *application) audit(r *http.Request, event, outcome string, user *data.User, email, detail string) {
+	entry := &data.AuditEntry{
+		Email:     email,
+		Event:     event,
+		Outcome:   outcome,
+		Detail:    detail,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	if user != nil {
+		entry.UserID = user.ID
+		entry.Email = user.Email
+	}
+
+	err := app.models.Audit.Insert(entry)
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// csvCell neutralises values a spreadsheet would otherwise run as a formula.
+// Emails, user agents and details such as identity provider errors come from
+// outside, so an attacker could plant one for an administrator to open. Every
+// text column goes through it, so a new source of text cannot be missed.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp")
+		return time.Time{}
+	}
+
+	return t
+}
+
+func (app *application) readAuditFilter(qs url.Values, v *validator.Validator) data.AuditFilter {
+	filter := data.AuditFilter{
+		UserID:  int64(app.readInt(qs, "user_id", 0, v)),
+		Event:   app.readString(qs, "event", ""),
+		Outcome: app.readString(qs, "outcome", ""),
+		From:    app.readTime(qs, "from", v),
+		To:      app.readTime(qs, "to", v),
+	}
+
+	data.ValidateAuditFilter(v, filter)
+
+	return filter
+}
+
+func (app *application) listAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	filter := app.readAuditFilter(qs, v)
+
+	filters := data.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 50, v),
+		Sort:         app.readString(qs, "sort", "-created_at"),
+		SortSafelist: []string{"created_at", "-created_at"},
+	}
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entries, metadata, err := app.models.Audit.GetAll(filter, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit_log": entries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exportAuditLogHandler streams the entries matching the same filters as
+// listAuditLogHandler as CSV, oldest first. Once the first row has been
+// written the status can no longer change, so later errors are only logged.
+func (app *application) exportAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	filter := app.readAuditFilter(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.csv"`)
+
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{"id", "created_at", "user_id", "email", "event", "outcome", "detail", "ip", "user_agent", "request_id"})
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	err = app.models.Audit.ForEach(filter, func(entry *data.AuditEntry) error {
+		userID := ""
+		if entry.UserID != 0 {
+			userID = strconv.FormatInt(entry.UserID, 10)
+		}
+
+		return cw.Write([]string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			userID,
+			csvCell(entry.Email),
+			csvCell(entry.Event),
+			csvCell(entry.Outcome),
+			csvCell(entry.Detail),
+			csvCell(entry.IP),
+			csvCell(entry.UserAgent),
+			csvCell(entry.RequestID),
+		})
+	})
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+)
+
+const (
+	maxImportBytes = 5 << 20
+	maxImportRows  = 2000
+
+	// Imported users did not ask for an account, so they get longer than the
+	// usual three days to notice the email.
+	importTokenTTL = 7 * 24 * time.Hour
+)
+
+// importableRoles leaves out admin on purpose: administrators are promoted one
+// at a time through the user management API.
+var importableRoles = []string{data.RoleAttendee, data.RoleUsher, data.RoleOrganizer}
+
+// importUsersHandler creates users from a CSV roster with name, email and
+// role columns. With ?dry_run=true it only validates the file. Either way a
+// file with any bad row is rejected as a whole, with one error per bad line.
+func (app *application) importUsersHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(s)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("dry_run must be a boolean value"))
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	rows, rowErrors, err := app.readImportRows(r.Body)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if len(rowErrors) == 0 {
+		emails := make([]string, len(rows))
+		for i, row := range rows {
+			emails[i] = row.User.Email
+		}
+
+		existing, err := app.models.Users.ExistingEmails(emails)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, row := range rows {
+			if existing[strings.ToLower(row.User.Email)] {
+				rowErrors[importLine(row.Line)] = "email: a user with this email address already exists"
+			}
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		app.failedValidationResponse(w, r, rowErrors)
+		return
+	}
+
+	if dryRun {
+		err = app.writeJSON(w, http.StatusOK, envelope{"dry_run": true, "valid_rows": len(rows)}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	imported, err := app.models.Users.Import(rows, importTokenTTL)
+	if err != nil {
+		var rowErr *data.RowError
+		switch {
+		case errors.As(err, &rowErr) && errors.Is(err, data.ErrDuplicateEmail):
+			app.failedValidationResponse(w, r, map[string]string{
+				importLine(rowErr.Line): "email: a user with this email address already exists",
+			})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	users := make([]*data.User, len(imported))
+	queued := 0
+	// Users whose welcome email could not be queued are listed in the
+	// response, so an administrator can send it again through
+	// POST /v1/admin/users/{id}/activation.
+	failed := []int64{}
+
+	for i, result := range imported {
+		users[i] = result.User
+
+		app.audit(r, data.AuditRegister, data.AuditSuccess, result.User, "", "bulk import")
+
+		err := app.queueMail(result.User.Email, "user_import.tmpl", map[string]any{
+			"name":               result.User.Name,
+			"activationToken":    result.ActivationToken.Plaintext,
+			"passwordResetToken": result.PasswordToken.Plaintext,
+		})
+		if err != nil {
+			app.logError(r, fmt.Errorf("queueing welcome email for user %d: %w", result.User.ID, err))
+			failed = append(failed, result.User.ID)
+			continue
+		}
+
+		queued++
+	}
+
+	env := envelope{"dry_run": false, "users": users, "emails_queued": queued, "emails_failed": failed}
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readImportRows parses the roster and validates each row on its own and
+// against the rows before it. Problems with individual rows are collected per
+// line; an error is only returned when the file as a whole is unusable.
+func (app *application) readImportRows(body io.Reader) ([]data.ImportRow, map[string]string, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("body must contain a CSV header row")
+		}
+		return nil, nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"name", "email"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("CSV header must include a %q column", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []data.ImportRow
+	rowErrors := map[string]string{}
+	seen := map[string]int{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if len(rows)+len(rowErrors) >= maxImportRows {
+			return nil, nil, fmt.Errorf("CSV must not contain more than %d rows", maxImportRows)
+		}
+
+		row := data.ImportRow{
+			Line: line,
+			User: &data.User{
+				Name:  field(record, "name"),
+				Email: field(record, "email"),
+			},
+			Role: strings.ToLower(field(record, "role")),
+		}
+		row.User.Password.SetUnusable()
+
+		if row.Role == "" {
+			row.Role = data.RoleAttendee
+		}
+
+		v := validator.New()
+
+		data.ValidateUser(v, row.User)
+		v.Check(validator.In(row.Role, importableRoles...), "role", "must be one of attendee, usher or organizer")
+
+		email := strings.ToLower(row.User.Email)
+		if first, ok := seen[email]; ok && row.User.Email != "" {
+			v.AddError("email", fmt.Sprintf("duplicates line %d", first))
+		} else {
+			seen[email] = line
+		}
+
+		if !v.Valid() {
+			rowErrors[importLine(line)] = joinErrors(v.Errors)
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 && len(rowErrors) == 0 {
+		return nil, nil, errors.New("CSV must contain at least one user")
+	}
+
+	return rows, rowErrors, nil
+}
+
+func importLine(line int) string {
+	return fmt.Sprintf("line %d", line)
+}
+
+// joinErrors flattens a row's field errors into one message, in a stable
+// order.
+func joinErrors(errs map[string]string) string {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, len(keys))
+	for i, key := range keys {
+		messages[i] = key + ": " + errs[key]
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const janitorBatchSize = 1000
+
+// sweepMutex stops a manual sweep from overlapping with a scheduled one.
+var sweepMutex sync.Mutex
+
+type sweepResult struct {
+	Tokens        int64 `json:"tokens"`
+	Denylist      int64 `json:"denylist"`
+	OIDCLogins    int64 `json:"oidc_logins"`
+	LoginAttempts int64 `json:"login_attempts"`
+}
+
+// cleanupExpiredTokens periodically deletes expired tokens, denylist entries,
+// abandoned OIDC logins and forgotten login attempts until the server shuts
+// down.
+func (app *application) cleanupExpiredTokens() {
+	app.background(func() {
+		ticker := time.NewTicker(app.config.janitor.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				_, err := app.sweepExpiredTokens()
+				if err != nil {
+					app.logger.Println(err)
+				}
+			case <-app.shutdown:
+				return
+			}
+		}
+	})
+}
+
+// sweepExpiredTokens deletes expired rows in batches of janitorBatchSize until
+// none are left or the server starts shutting down.
+func (app *application) sweepExpiredTokens() (sweepResult, error) {
+	sweepMutex.Lock()
+	defer sweepMutex.Unlock()
+
+	var result sweepResult
+
+	batches := []struct {
+		deleteExpired func(limit int) (int64, error)
+		count         *int64
+	}{
+		{app.models.Tokens.DeleteExpired, &result.Tokens},
+		{app.models.Denylist.DeleteExpired, &result.Denylist},
+		{app.models.OIDCLogins.DeleteExpired, &result.OIDCLogins},
+		{app.models.Lockouts.DeleteExpired, &result.LoginAttempts},
+	}
+
+	for _, batch := range batches {
+		for {
+			deleted, err := batch.deleteExpired(janitorBatchSize)
+			if err != nil {
+				return result, err
+			}
+
+			*batch.count += deleted
+
+			if deleted < janitorBatchSize || app.shuttingDown() {
+				break
+			}
+		}
+	}
+
+	app.logger.Printf("token janitor deleted %d expired tokens, %d denylist entries, %d OIDC logins and %d login attempts", result.Tokens, result.Denylist, result.OIDCLogins, result.LoginAttempts)
+
+	return result, nil
+}
+
+func (app *application) shuttingDown() bool {
+	select {
+	case <-app.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (app *application) sweepExpiredTokensHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := app.sweepExpiredTokens()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"deleted": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
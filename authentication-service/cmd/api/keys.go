@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
+	"github.com/pascaldekloe/jwt"
+)
+
+const accessTokenTTL = 15 * time.Minute
+
+// keyring holds the signing keys currently loaded from the database. The first
+// key is the newest one and is used for signing; every key in the ring is
+// accepted when verifying.
+type keyring struct {
+	mu       sync.RWMutex
+	keys     []*data.SigningKey
+	register *jwt.KeyRegister
+}
+
+func (k *keyring) set(keys []*data.SigningKey) {
+	register := &jwt.KeyRegister{}
+	for _, key := range keys {
+		register.EdDSAs = append(register.EdDSAs, key.PublicKey())
+		register.EdDSAIDs = append(register.EdDSAIDs, key.ID)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = keys
+	k.register = register
+}
+
+func (k *keyring) current() *data.SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if len(k.keys) == 0 {
+		return nil
+	}
+	return k.keys[0]
+}
+
+func (k *keyring) all() []*data.SigningKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.keys
+}
+
+func (k *keyring) check(token []byte) (*jwt.Claims, error) {
+	k.mu.RLock()
+	register := k.register
+	k.mu.RUnlock()
+
+	if register == nil {
+		return nil, errors.New("no signing keys loaded")
+	}
+	return register.Check(token)
+}
+
+// loadSigningKeys reloads the active keys from the database and generates a new
+// signing key when the newest one is older than the rotation period. Retired
+// keys stay valid for verification until every token they signed has expired.
+func (app *application) loadSigningKeys() error {
+	keys, err := app.models.SigningKeys.GetAllActive()
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 || time.Since(keys[0].CreatedAt) >= app.config.jwt.rotationPeriod {
+		key, err := app.models.SigningKeys.New(app.config.jwt.rotationPeriod + accessTokenTTL + time.Minute)
+		if err != nil {
+			return err
+		}
+
+		app.logger.Printf("generated signing key %s", key.ID)
+		keys = append([]*data.SigningKey{key}, keys...)
+	}
+
+	app.keys.set(keys)
+	return nil
+}
+
+func (app *application) rotateSigningKeys() {
+	app.background(func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := app.loadSigningKeys()
+				if err != nil {
+					app.logger.Println(err)
+				}
+			case <-app.shutdown:
+				return
+			}
+		}
+	})
+}
+
+func (app *application) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	keys := []map[string]string{}
+
+	for _, key := range app.keys.all() {
+		keys = append(keys, map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"use": "sig",
+			"alg": jwt.EdDSA,
+			"kid": key.ID,
+			"x":   base64.RawURLEncoding.EncodeToString(key.PublicKey()),
+		})
+	}
+
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=300")
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"keys": keys}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
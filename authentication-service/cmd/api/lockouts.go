@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+)
+
+// A single address is allowed more failures than a single account, since many
+// students log in from behind the same campus NAT.
+const (
+	accountLockoutThreshold = 5
+	ipLockoutThreshold      = 20
+)
+
+// clientIP returns the address lockouts are counted against. realIP has
+// already replaced RemoteAddr with the client's address if, and only if, the
+// request came through a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// loginRetryAfter reports how long the account and address behind a login
+// attempt must wait before trying again. A zero duration means neither is
+// locked.
+func (app *application) loginRetryAfter(email, ip string) (time.Duration, error) {
+	var retryAfter time.Duration
+
+	subjects := map[string]string{
+		data.LockoutAccount: strings.ToLower(email),
+		data.LockoutIP:      ip,
+	}
+
+	for kind, subject := range subjects {
+		lockout, err := app.models.Lockouts.Get(kind, subject)
+		if err != nil {
+			if errors.Is(err, data.ErrRecordNotFound) {
+				continue
+			}
+			return 0, err
+		}
+
+		if lockout.RetryAfter() > retryAfter {
+			retryAfter = lockout.RetryAfter()
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// recordLoginFailure counts a failed attempt against both the account and the
+// address. When the account is locked for the first time its owner is sent a
+// one-time unlock token, so a legitimate user does not have to wait it out.
+func (app *application) recordLoginFailure(user *data.User, email, ip string) error {
+	_, err := app.models.Lockouts.RecordFailure(data.LockoutIP, ip, ipLockoutThreshold)
+	if err != nil {
+		return err
+	}
+
+	lockout, err := app.models.Lockouts.RecordFailure(data.LockoutAccount, strings.ToLower(email), accountLockoutThreshold)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || lockout.Failures != accountLockoutThreshold {
+		return nil
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeUnlock, user.ID)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeUnlock)
+	if err != nil {
+		return err
+	}
+
+	app.background(func() {
+		data := map[string]any{
+			"unlockToken": token.Plaintext,
+		}
+
+		err := app.mailer.Send(user.Email, "token_account_unlock.tmpl", data)
+		if err != nil {
+			app.logger.Println(err)
+		}
+	})
+
+	return nil
+}
+
+func (app *application) unlockAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeUnlock, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired unlock token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Lockouts.Clear(data.LockoutAccount, strings.ToLower(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeUnlock, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your account has been unlocked"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
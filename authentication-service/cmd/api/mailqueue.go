@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+const mailQueueSize = 5000
+
+var errMailQueueFull = errors.New("mail queue is full")
+
+type mailJob struct {
+	recipient    string
+	templateFile string
+	data         any
+}
+
+// queueMail hands an email to the mail worker instead of sending it straight
+// away. It is meant for bulk sends, which would otherwise open hundreds of
+// SMTP connections at once.
+func (app *application) queueMail(recipient, templateFile string, data any) error {
+	select {
+	case app.mailQueue <- mailJob{recipient: recipient, templateFile: templateFile, data: data}:
+		return nil
+	default:
+		return errMailQueueFull
+	}
+}
+
+// sendQueuedMail sends queued emails one at a time, at most one per
+// MAIL_SEND_INTERVAL, until the server shuts down. Emails still queued at that
+// point are dropped and counted in the log; their tokens stay valid, so they
+// can be resent from the admin API.
+func (app *application) sendQueuedMail() {
+	app.background(func() {
+		ticker := time.NewTicker(app.config.smtp.sendInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case job := <-app.mailQueue:
+				err := app.mailer.Send(job.recipient, job.templateFile, job.data)
+				if err != nil {
+					app.logger.Println(err)
+				}
+
+				select {
+				case <-ticker.C:
+				case <-app.shutdown:
+					app.logger.Printf("dropping %d queued emails", len(app.mailQueue))
+					return
+				}
+			case <-app.shutdown:
+				app.logger.Printf("dropping %d queued emails", len(app.mailQueue))
+				return
+			}
+		}
+	})
+}
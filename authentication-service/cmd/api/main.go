@@ -2,15 +2,20 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
+	"encoding/base64"
 	"log"
-	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/mailer"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/migrate"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/oidc"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/migrations"
 	_ "github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4"
 	_ "github.com/jackc/pgx/v4/stdlib"
@@ -32,20 +37,61 @@ type config struct {
 		username string
 		password string
 		sender   string
+		// sendInterval is the minimum gap between emails sent from the
+		// mail queue.
+		sendInterval time.Duration
 	}
 	jwt struct {
+		rotationPeriod time.Duration
+		// encryptionKey is the AES-256 key the signing keys are encrypted
+		// with in the database.
+		encryptionKey []byte
+	}
+	janitor struct {
+		interval time.Duration
+	}
+	registration struct {
+		allowedDomains []string
+	}
+	password struct {
+		minEntropy float64
+	}
+	magicLink struct {
+		enabled bool
+	}
+	// introspection holds the secret internal services present to the
+	// introspection endpoint, which refuses every caller while it is empty.
+	introspection struct {
 		secret string
 	}
+	// oidc configures login through an external OpenID provider, which is
+	// off unless an issuer URL is set.
+	oidc oidc.Config
 }
 
 type application struct {
-	config config
-	logger *log.Logger
-	models data.Models
-	mailer mailer.Mailer
+	config         config
+	logger         *log.Logger
+	models         data.Models
+	mailer         mailer.Mailer
+	keys           *keyring
+	oidc           *oidc.Provider
+	passwordPolicy *validator.PasswordPolicy
+	proxies        *trustedProxies
+	mailQueue      chan mailJob
+	shutdown       chan struct{}
+	wg             sync.WaitGroup
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrateCommand(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	var cfg config
 	cfg.port = webPort
 	cfg.env = webEnv
@@ -54,44 +100,142 @@ func main() {
 	cfg.smtp.username = os.Getenv("MAILHOG_USERNAME")
 	cfg.smtp.password = os.Getenv("MAILHOG_PASSWORD")
 	cfg.smtp.sender = os.Getenv("SENDER_EMAIL")
-	cfg.jwt.secret = os.Getenv("JWT_SECRET")
+	cfg.jwt.rotationPeriod = 30 * 24 * time.Hour
+
+	encryptionKey, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(encryptionKey) != 32 {
+		log.Fatal("JWT_KEY_ENCRYPTION_KEY must be set to 32 base64-encoded bytes")
+	}
+	cfg.jwt.encryptionKey = encryptionKey
+
+	if rotation := os.Getenv("JWT_KEY_ROTATION_PERIOD"); rotation != "" {
+		period, err := time.ParseDuration(rotation)
+		if err != nil {
+			log.Fatalf("Error: Invalid JWT_KEY_ROTATION_PERIOD value: %s\n", rotation)
+		}
+		cfg.jwt.rotationPeriod = period
+	}
+
+	cfg.smtp.sendInterval = 200 * time.Millisecond
+
+	if interval := os.Getenv("MAIL_SEND_INTERVAL"); interval != "" {
+		period, err := time.ParseDuration(interval)
+		if err != nil || period <= 0 {
+			log.Fatalf("Error: Invalid MAIL_SEND_INTERVAL value: %s\n", interval)
+		}
+		cfg.smtp.sendInterval = period
+	}
+
+	cfg.janitor.interval = time.Hour
+	cfg.password.minEntropy = 45
+
+	if entropy := os.Getenv("PASSWORD_MIN_ENTROPY"); entropy != "" {
+		bits, err := strconv.ParseFloat(entropy, 64)
+		if err != nil || bits < 0 {
+			log.Fatalf("Error: Invalid PASSWORD_MIN_ENTROPY value: %s\n", entropy)
+		}
+		cfg.password.minEntropy = bits
+	}
+
+	for _, domain := range strings.Split(os.Getenv("ALLOWED_EMAIL_DOMAINS"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			cfg.registration.allowedDomains = append(cfg.registration.allowedDomains, domain)
+		}
+	}
+
+	if interval := os.Getenv("TOKEN_CLEANUP_INTERVAL"); interval != "" {
+		period, err := time.ParseDuration(interval)
+		if err != nil || period <= 0 {
+			log.Fatalf("Error: Invalid TOKEN_CLEANUP_INTERVAL value: %s\n", interval)
+		}
+		cfg.janitor.interval = period
+	}
+
+	if enabled := os.Getenv("MAGIC_LINK_ENABLED"); enabled != "" {
+		var err error
+		cfg.magicLink.enabled, err = strconv.ParseBool(enabled)
+		if err != nil {
+			log.Fatalf("Error: Invalid MAGIC_LINK_ENABLED value: %s\n", enabled)
+		}
+	}
+
+	cfg.introspection.secret = os.Getenv("TOKEN_INTROSPECTION_SECRET")
+
+	cfg.oidc.IssuerURL = os.Getenv("OIDC_ISSUER_URL")
+	cfg.oidc.ClientID = os.Getenv("OIDC_CLIENT_ID")
+	cfg.oidc.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	cfg.oidc.RedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+
+	if cfg.oidc.IssuerURL != "" && (cfg.oidc.ClientID == "" || cfg.oidc.RedirectURL == "") {
+		log.Fatal("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set when OIDC_ISSUER_URL is")
+	}
 
 	if cfg.smtp.host == "" || portStr == "" {
 		log.Fatal("Environment variables for Mailhog are not set")
 	}
-	var err error
 	cfg.smtp.port, err = strconv.Atoi(portStr)
 	if err != nil {
-		log.Fatal("Error: Invalid MAILHOG_PORT value: %s\n", portStr)
+		log.Fatalf("Error: Invalid MAILHOG_PORT value: %s\n", portStr)
 		return
 	}
 
+	passwordPolicy, err := validator.NewPasswordPolicy(cfg.password.minEntropy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	db := connectToDB()
 	if db == nil {
 		log.Panic("could not connect to database")
 	}
 
+	models := data.NewModels(db)
+	models.SigningKeys.EncryptionKey = cfg.jwt.encryptionKey
+
 	logger := log.New(os.Stdout, "", log.Ldate|log.LUTC)
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:         cfg,
+		logger:         logger,
+		models:         models,
+		mailer:         mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		keys:           &keyring{},
+		passwordPolicy: passwordPolicy,
+		proxies:        proxies,
+		mailQueue:      make(chan mailJob, mailQueueSize),
+		shutdown:       make(chan struct{}),
 	}
 
-	log.Printf("starting user service on %s\n", cfg.port)
+	if cfg.oidc.IssuerURL != "" {
+		app.oidc = oidc.New(cfg.oidc)
+	}
 
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.port),
-		Handler:      app.routes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	encrypted, err := app.models.SigningKeys.EncryptPlaintext()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if encrypted > 0 {
+		logger.Printf("encrypted %d signing keys stored in plaintext", encrypted)
+	}
+
+	err = app.loadSigningKeys()
+	if err != nil {
+		log.Fatal(err)
 	}
+	app.rotateSigningKeys()
+	app.cleanupExpiredTokens()
+	app.sendQueuedMail()
 
-	logger.Printf("starting %s server on %s", cfg.env, srv.Addr)
-	err = srv.ListenAndServe()
-	log.Fatal(err)
+	log.Printf("starting user service on %s\n", cfg.port)
+
+	err = app.serve()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func openDB(dsn string) (*sql.DB, error) {
@@ -116,6 +260,20 @@ func connectToDB() *sql.DB {
 			counts++
 		} else {
 			log.Println("Connected to Database!")
+
+			migrator, err := migrate.New(connection, migrations.Files)
+			if err != nil {
+				log.Println(err)
+				return nil
+			}
+
+			applied, err := migrator.Up()
+			if err != nil {
+				log.Println(err)
+				return nil
+			}
+			log.Printf("Applied %d database migrations\n", applied)
+
 			return connection
 		}
 		if counts > 10 {
@@ -1,61 +1,52 @@
 package main
 
 import (
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pascaldekloe/jwt"
 )
 
-func (app *application) requireAdmin(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+var errInvalidToken = errors.New("invalid token")
 
-		authorizationHeader := r.Header.Get("Authorization")
-		if authorizationHeader == "" {
-			app.authenticationRequiredResponse(w, r)
-			return
-		}
+// verifyAccessToken checks an access token's signature, lifetime, issuer and
+// audience and makes sure it has not been revoked. It returns errInvalidToken
+// for any token that should be rejected.
+func (app *application) verifyAccessToken(token []byte) (*jwt.Claims, error) {
+	claims, err := app.keys.check(token)
+	if err != nil {
+		return nil, errInvalidToken
+	}
 
-		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			http.Error(w, "Invalid token format", http.StatusUnauthorized)
-			return
-		}
-		token := headerParts[1]
+	if !claims.Valid(time.Now()) {
+		return nil, errInvalidToken
+	}
 
-		claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secret))
+	if claims.Issuer != "giu-event-hub.com" || !claims.AcceptAudience("giu-event-hub.com") {
+		return nil, errInvalidToken
+	}
 
-		if err != nil {
-			app.notFoundResponse(w, r)
-			return
-		}
+	if claims.ID == "" {
+		return nil, errInvalidToken
+	}
 
-		if isAdmin, ok := claims.Set["isAdmin"].(bool); !ok || !isAdmin {
-			app.notFoundResponse(w, r)
-			return
-		}
-
-		if !claims.Valid(time.Now()) {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
+	revoked, err := app.models.Denylist.Contains(claims.ID)
+	if err != nil {
+		return nil, err
+	}
 
-		if claims.Issuer != "giu-event-hub.com" {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
-
-		if !claims.AcceptAudience("giu-event-hub.com") {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
+	if revoked {
+		return nil, errInvalidToken
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	return claims, nil
 }
 
-func (app *application) requireUser(next http.Handler) http.Handler {
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		authorizationHeader := r.Header.Get("Authorization")
@@ -71,27 +62,40 @@ func (app *application) requireUser(next http.Handler) http.Handler {
 		}
 		token := headerParts[1]
 
-		claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secret))
+		claims, err := app.verifyAccessToken([]byte(token))
 		if err != nil {
-			app.invalidAuthenticationTokenResponse(w, r)
+			switch {
+			case errors.Is(err, errInvalidToken):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
 			return
 		}
 
-		if !claims.Valid(time.Now()) {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
+		r = app.contextSetClaims(r, claims)
 
-		if claims.Issuer != "giu-event-hub.com" {
-			app.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		if !claims.AcceptAudience("giu-event-hub.com") {
-			app.invalidAuthenticationTokenResponse(w, r)
+func (app *application) requireAdmin(next http.Handler) http.Handler {
+	return app.requireAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := app.contextGetClaims(r)
+
+		if isAdmin, ok := claims.Set["isAdmin"].(bool); !ok || !isAdmin {
+			app.notFoundResponse(w, r)
 			return
 		}
 
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func (app *application) requireUser(next http.Handler) http.Handler {
+	return app.requireAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := app.contextGetClaims(r)
+
 		if isActivated, ok := claims.Set["isActivated"].(bool); !ok || !isActivated {
 			app.inactiveAccountResponse(w, r)
 			return
@@ -102,6 +106,52 @@ func (app *application) requireUser(next http.Handler) http.Handler {
 			return
 		}
 
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// requirePermission returns middleware that only lets through callers whose
+// roles grant the permission with the given code.
+func (app *application) requirePermission(code string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return app.requireAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := app.contextGetClaims(r)
+
+			userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+			if err != nil {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			permissions, err := app.models.Permissions.GetAllForUser(userID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			if !permissions.Include(code) {
+				app.notPermittedResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// requireIntrospectionClient only lets through internal services that present
+// the introspection secret, so the endpoint cannot be used from outside to
+// probe tokens and API keys.
+func (app *application) requireIntrospectionClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if app.config.introspection.secret == "" ||
+			subtle.ConstantTimeCompare([]byte(secret), []byte(app.config.introspection.secret)) != 1 {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/oidc"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+)
+
+// oidcLoginTTL is how long a user has to finish logging in at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// createOIDCAuthorizationHandler starts a login at the configured OpenID
+// provider. The client keeps the returned binding to itself and sends the user
+// to the returned URL; the provider sends them back to the redirect URL with a
+// code and the state, which the client then posts to /v1/tokens/oidc along
+// with the binding. As the binding never appears in a URL, a code and state
+// planted in someone else's browser cannot be redeemed there (login CSRF).
+func (app *application) createOIDCAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	login := &data.OIDCLogin{Expiry: time.Now().Add(oidcLoginTTL)}
+
+	for _, s := range []*string{&login.State, &login.Binding, &login.Nonce, &login.CodeVerifier} {
+		var err error
+		*s, err = oidc.RandomString()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	url, err := app.oidc.AuthCodeURL(r.Context(), login.State, login.Nonce, login.CodeVerifier)
+	if err != nil {
+		app.identityProviderErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.OIDCLogins.Insert(login)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"authorization_url": url, "state": login.State, "binding": login.Binding, "expiry": login.Expiry}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createOIDCAuthenticationTokenHandler finishes a login at the OpenID
+// provider. The user is matched on the verified email address in the ID token,
+// and a first login from an allowed domain creates the account. Either way the
+// user ends up with the same tokens as after a password login.
+func (app *application) createOIDCAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Code    string `json:"code"`
+		State   string `json:"state"`
+		Binding string `json:"binding"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Code != "", "code", "must be provided")
+	v.Check(input.State != "", "state", "must be provided")
+	v.Check(input.Binding != "", "binding", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	login, err := app.models.OIDCLogins.Consume(input.State, input.Binding)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.audit(r, data.AuditLogin, data.AuditFailure, nil, "", "oidc: unknown or expired state or wrong binding")
+			v.AddError("state", "unknown or expired, please start the login again")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	identity, err := app.oidc.Exchange(r.Context(), input.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrExchangeFailed), errors.Is(err, oidc.ErrInvalidIDToken):
+			app.logError(r, err)
+			app.audit(r, data.AuditLogin, data.AuditFailure, nil, "", "oidc: "+err.Error())
+			app.invalidCredentialsResponse(w, r)
+		default:
+			app.identityProviderErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !identity.EmailVerified {
+		app.audit(r, data.AuditLogin, data.AuditFailure, nil, identity.Email, "oidc: email not verified")
+		v.AddError("email", "must be verified by the identity provider")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if data.ValidateEmail(v, identity.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.oidcUser(r, identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCDomainNotAllowed):
+			app.audit(r, data.AuditRegister, data.AuditFailure, nil, identity.Email, "oidc: email domain not allowed")
+			v.AddError("email", "must belong to an allowed domain")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.completeLogin(w, r, user, "oidc")
+}
+
+var errOIDCDomainNotAllowed = errors.New("email domain not allowed")
+
+// oidcUser returns the user with the identity's email address, creating an
+// activated attendee when there is none. The provider has verified the
+// address, so an existing account that was never activated is activated now.
+// Disabled accounts are left alone for completeLogin to turn away; an
+// administrator's decision is not undone by logging in elsewhere.
+func (app *application) oidcUser(r *http.Request, identity *oidc.Identity) (*data.User, error) {
+	user, err := app.models.Users.GetByEmail(identity.Email)
+	switch {
+	case err == nil:
+		if !user.Activated && !user.Disabled {
+			user.Activated = true
+
+			err = app.models.Users.Update(user)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	case !errors.Is(err, data.ErrRecordNotFound):
+		return nil, err
+	}
+
+	if !app.emailDomainAllowed(identity.Email) {
+		return nil, errOIDCDomainNotAllowed
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = identity.Email
+	}
+
+	user = &data.User{
+		Name:      name,
+		Email:     identity.Email,
+		Activated: true,
+	}
+
+	// The user logs in through the provider and has no password here until
+	// they set one with a password reset.
+	user.Password.SetUnusable()
+
+	err = app.models.Users.Insert(user)
+	if err != nil {
+		// Someone else got there first, most likely the same user logging in
+		// twice at once.
+		if errors.Is(err, data.ErrDuplicateEmail) {
+			return app.models.Users.GetByEmail(identity.Email)
+		}
+		return nil, err
+	}
+
+	err = app.models.Roles.AddForUser(user.ID, data.RoleAttendee)
+	if err != nil {
+		return nil, err
+	}
+
+	app.audit(r, data.AuditRegister, data.AuditSuccess, user, "", "oidc")
+
+	return user, nil
+}
@@ -14,15 +14,15 @@ func (app *application) routes() http.Handler {
 
 	mux.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Expected-Version", "X-API-Key"},
+		ExposedHeaders:   []string{"Link", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	mux.Use(middleware.RequestID)
-	mux.Use(middleware.RealIP)
+	mux.Use(app.realIP)
 	mux.Use(middleware.Logger)
 	mux.Use(middleware.Recoverer)
 	mux.Use(middleware.Timeout(60 * time.Second))
@@ -32,10 +32,63 @@ func (app *application) routes() http.Handler {
 	mux.NotFound(http.HandlerFunc(app.notFoundResponse))
 	mux.MethodNotAllowed(http.HandlerFunc(app.methodNotAllowedResponse))
 
+	mux.Get("/.well-known/jwks.json", app.jwksHandler)
+
 	mux.Post("/v1/users", app.registerUserHandler)
 	mux.Post("/v1/tokens/activation", app.createActivationTokenHandler)
 	mux.Put("/v1/users/activated", app.activateUserHandler)
 	mux.Post("/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	mux.Post("/v1/tokens/mfa", app.createMFAAuthenticationTokenHandler)
+	mux.Post("/v1/tokens/magic-link", app.createMagicLinkTokenHandler)
+	mux.Post("/v1/tokens/magic-link/authentication", app.createMagicLinkAuthenticationTokenHandler)
+	mux.Get("/v1/oidc/authorize", app.createOIDCAuthorizationHandler)
+	mux.Post("/v1/tokens/oidc", app.createOIDCAuthenticationTokenHandler)
+	mux.Post("/v1/tokens/refresh", app.refreshAuthenticationTokenHandler)
+	mux.With(app.requireIntrospectionClient).Post("/v1/tokens/introspect", app.introspectTokenHandler)
+	mux.With(app.requireAuthentication).Delete("/v1/tokens", app.deleteAuthenticationTokenHandler)
+	mux.Get("/v1/tokens/denylist/{jti}", app.showDenylistHandler)
+	mux.Post("/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+	mux.Put("/v1/users/password", app.updateUserPasswordHandler)
+	mux.Put("/v1/users/unlocked", app.unlockAccountHandler)
+	mux.Put("/v1/users/email", app.confirmEmailChangeHandler)
+
+	mux.With(app.requireUser).Get("/v1/users/me", app.showCurrentUserHandler)
+	mux.With(app.requireUser).Patch("/v1/users/me", app.updateCurrentUserHandler)
+
+	mux.With(app.requireAuthentication).Post("/v1/users/me/totp", app.createTOTPHandler)
+	mux.With(app.requireAuthentication).Put("/v1/users/me/totp", app.confirmTOTPHandler)
+	mux.With(app.requireAuthentication).Delete("/v1/users/me/totp", app.deleteTOTPHandler)
+
+	mux.With(app.requireIntrospectionClient).Post("/v1/api-keys/verification", app.verifyAPIKeyHandler)
+	mux.With(app.requireAuthentication).Get("/v1/api-keys", app.listAPIKeysHandler)
+	mux.With(app.requireAuthentication).Post("/v1/api-keys", app.createAPIKeyHandler)
+	mux.With(app.requireAuthentication).Delete("/v1/api-keys/{id}", app.deleteAPIKeyHandler)
+
+	mux.With(app.requireAdmin).Post("/v1/admin/tokens/cleanup", app.sweepExpiredTokensHandler)
+
+	mux.With(app.requireAdmin).Get("/v1/admin/audit-log", app.listAuditLogHandler)
+	mux.With(app.requireAdmin).Get("/v1/admin/audit-log/export", app.exportAuditLogHandler)
+
+	mux.Route("/v1/admin/invitations", func(mux chi.Router) {
+		mux.Use(app.requireAdmin)
+
+		mux.Get("/", app.listInvitationsHandler)
+		mux.Post("/", app.createInvitationHandler)
+		mux.Delete("/{id}", app.deleteInvitationHandler)
+	})
+
+	mux.Route("/v1/admin/users", func(mux chi.Router) {
+		mux.Use(app.requirePermission("users:manage"))
+
+		mux.Get("/", app.listUsersHandler)
+		mux.Post("/import", app.importUsersHandler)
+		mux.Get("/{id}", app.showUserHandler)
+		mux.Patch("/{id}", app.updateUserStatusHandler)
+		mux.Post("/{id}/activation", app.resendActivationTokenHandler)
+		mux.Delete("/{id}/lockout", app.clearLockoutHandler)
+		mux.Put("/{id}/roles/{role}", app.grantUserRoleHandler)
+		mux.Delete("/{id}/roles/{role}", app.revokeUserRoleHandler)
+	})
 
 	return mux
 }
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+	"github.com/go-chi/chi/v5"
 	"github.com/pascaldekloe/jwt"
 )
 
@@ -44,12 +49,19 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	if user.Disabled {
+		v.AddError("email", "user account has been disabled")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	app.audit(r, data.AuditTokenCreate, data.AuditSuccess, user, "", data.ScopeActivation)
+
 	app.background(func() {
 		data := map[string]interface{}{
 			"activationToken": token.Plaintext,
@@ -68,6 +80,205 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 	}
 }
 
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no matching email address found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !user.Activated {
+		v.AddError("email", "user account must be activated")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if user.Disabled {
+		v.AddError("email", "user account has been disabled")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditTokenCreate, data.AuditSuccess, user, "", data.ScopePasswordReset)
+
+	app.background(func() {
+		data := map[string]any{
+			"passwordResetToken": token.Plaintext,
+		}
+
+		err = app.mailer.Send(user.Email, "token_password_reset.tmpl", data)
+		if err != nil {
+			app.logger.Println(err)
+		}
+	})
+
+	env := envelope{"message": "an email will be sent to you containing password reset instructions"}
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createMagicLinkTokenHandler emails a single-use login token, letting users
+// who have forgotten their password log in without resetting it. Deployments
+// opt in with MAGIC_LINK_ENABLED.
+func (app *application) createMagicLinkTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.config.magicLink.enabled {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no matching email address found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !user.Activated {
+		v.AddError("email", "user account must be activated")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if user.Disabled {
+		v.AddError("email", "user account has been disabled")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeMagicLink, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 15*time.Minute, data.ScopeMagicLink)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditTokenCreate, data.AuditSuccess, user, "", data.ScopeMagicLink)
+
+	app.background(func() {
+		data := map[string]any{
+			"magicLinkToken": token.Plaintext,
+		}
+
+		err = app.mailer.Send(user.Email, "token_magic_link.tmpl", data)
+		if err != nil {
+			app.logger.Println(err)
+		}
+	})
+
+	env := envelope{"message": "an email will be sent to you containing a login link"}
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createMagicLinkAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if !app.config.magicLink.enabled {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeMagicLink, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.audit(r, data.AuditLogin, data.AuditFailure, nil, "", "invalid magic link")
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Deleting the token before logging in means a link that is clicked twice,
+	// or intercepted and raced, only works once.
+	err = app.models.Tokens.DeleteForToken(data.ScopeMagicLink, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.completeLogin(w, r, user, data.ScopeMagicLink)
+}
+
 func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email    string `json:"email"`
@@ -90,10 +301,30 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
+	ip := clientIP(r)
+
+	retryAfter, err := app.loginRetryAfter(input.Email, ip)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter > 0 {
+		app.audit(r, data.AuditLogin, data.AuditFailure, nil, input.Email, "locked out")
+		app.lockedOutResponse(w, r, retryAfter)
+		return
+	}
+
 	user, err := app.models.Users.GetByEmail(input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.audit(r, data.AuditLogin, data.AuditFailure, nil, input.Email, "unknown email")
+			err = app.recordLoginFailure(nil, input.Email, ip)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 			app.invalidCredentialsResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -108,27 +339,246 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	if !match {
+		app.audit(r, data.AuditLogin, data.AuditFailure, user, "", "wrong password")
+		err = app.recordLoginFailure(user, input.Email, ip)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 		app.invalidCredentialsResponse(w, r)
 		return
 	}
 
+	// Hashes from before argon2id, or with outdated parameters, can only be
+	// replaced while the plaintext is at hand. A failure here must not stop
+	// the login; the next one will try again.
+	if user.Password.NeedsRehash() {
+		err = user.Password.Set(input.Password)
+		if err == nil {
+			err = app.models.Users.Update(user)
+		}
+		if err != nil {
+			app.logError(r, fmt.Errorf("rehashing password for user %d: %w", user.ID, err))
+		}
+	}
+
+	app.completeLogin(w, r, user, "password")
+}
+
+// completeLogin finishes a login once the user has proven who they are with a
+// first factor, named by method for the audit log. With 2FA enabled this only
+// earns a short-lived token that has to be exchanged, together with a code, at
+// /v1/tokens/mfa. Disabled accounts are turned away here, after the first
+// factor, so the response does not tell strangers an account is disabled.
+func (app *application) completeLogin(w http.ResponseWriter, r *http.Request, user *data.User, method string) {
+	if user.Disabled {
+		app.audit(r, data.AuditLogin, data.AuditFailure, user, "", "account disabled")
+		app.disabledAccountResponse(w, r)
+		return
+	}
+
+	mfaEnabled, err := app.models.MFA.Enabled(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if mfaEnabled {
+		token, err := app.models.Tokens.New(user.ID, mfaTokenTTL, data.ScopeMFA)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.audit(r, data.AuditTokenCreate, data.AuditSuccess, user, "", data.ScopeMFA)
+
+		env := envelope{
+			"mfa_required": true,
+			"mfa_token":    token.Plaintext,
+			"mfa_expiry":   token.Expiry,
+		}
+
+		err = app.writeJSON(w, http.StatusAccepted, env, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Lockouts.Clear(data.LockoutAccount, strings.ToLower(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env, err := app.createTokenPair(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditLogin, data.AuditSuccess, user, "", method)
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.RefreshToken != "", "refresh_token", "must be provided")
+	v.Check(len(input.RefreshToken) == 26, "refresh_token", "must be 26 bytes long")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopeRefresh, input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.audit(r, data.AuditTokenCreate, data.AuditFailure, nil, "", "invalid refresh token")
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Refresh tokens are single use. Deleting the row before issuing a new pair
+	// means two concurrent requests with the same token cannot both succeed.
+	err = app.models.Tokens.DeleteForToken(data.ScopeRefresh, input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.Disabled {
+		app.audit(r, data.AuditTokenCreate, data.AuditFailure, user, "", "account disabled")
+		app.disabledAccountResponse(w, r)
+		return
+	}
+
+	env, err := app.createTokenPair(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.audit(r, data.AuditTokenCreate, data.AuditSuccess, user, "", data.ScopeRefresh)
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	claims := app.contextGetClaims(r)
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	err = app.models.Denylist.Insert(claims.ID, claims.Expires.Time())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeRefresh, userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been logged out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showDenylistHandler(w http.ResponseWriter, r *http.Request) {
+	jti := chi.URLParam(r, "jti")
+
+	revoked, err := app.models.Denylist.Contains(jti)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revoked": revoked}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createTokenPair(user *data.User) (envelope, error) {
+	jti := make([]byte, 16)
+	_, err := rand.Read(jti)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := app.models.Roles.GetAllForUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := app.keys.current()
+	if key == nil {
+		return nil, errors.New("no signing key available")
+	}
+
+	expiry := time.Now().Add(accessTokenTTL)
+
 	var claims jwt.Claims
+	claims.KeyID = key.ID
+	claims.ID = hex.EncodeToString(jti)
 	claims.Subject = strconv.FormatInt(user.ID, 10)
 	claims.Issued = jwt.NewNumericTime(time.Now())
 	claims.NotBefore = jwt.NewNumericTime(time.Now())
-	claims.Expires = jwt.NewNumericTime(time.Now().Add(24 * time.Hour))
+	claims.Expires = jwt.NewNumericTime(expiry)
 	claims.Issuer = "giu-event-hub.com"
 	claims.Audiences = []string{"giu-event-hub.com"}
-	claims.Set = map[string]any{"isAdmin": user.IsAdmin, "isActivated": user.Activated, "email": user.Email, "name": user.Name}
+	claims.Set = map[string]any{"isAdmin": user.IsAdmin, "isActivated": user.Activated, "email": user.Email, "name": user.Name, "roles": roles}
 
-	jwtBytes, err := claims.HMACSign(jwt.HS256, []byte(app.config.jwt.secret))
+	jwtBytes, err := claims.EdDSASign(key.PrivateKey)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+		return nil, err
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": string(jwtBytes)}, nil)
+	refreshToken, err := app.models.Tokens.New(user.ID, 7*24*time.Hour, data.ScopeRefresh)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		return nil, err
+	}
+
+	env := envelope{
+		"authentication_token": string(jwtBytes),
+		"expiry":               expiry,
+		"refresh_token":        refreshToken.Plaintext,
+		"refresh_expiry":       refreshToken.Expiry,
 	}
+
+	return env, nil
 }
@@ -3,18 +3,42 @@ package main
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/data"
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
 )
 
+// emailDomainAllowed reports whether email belongs to one of the configured
+// domains or a subdomain of one. With no domains configured every address is
+// allowed.
+func (app *application) emailDomainAllowed(email string) bool {
+	if len(app.config.registration.allowedDomains) == 0 {
+		return true
+	}
+
+	_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok {
+		return false
+	}
+
+	for _, allowed := range app.config.registration.allowedDomains {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Name           string `json:"name"`
+		Email          string `json:"email"`
+		Password       string `json:"password"`
+		InvitationCode string `json:"invitation_code"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -36,15 +60,51 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	v := validator.New()
-	if data.ValidateUser(v, user); !v.Valid() {
+
+	data.ValidateUser(v, user)
+	app.passwordPolicy.Check(v, input.Password, user.Email, user.Name)
+
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	// Addresses outside the allowed domains can only register with an
+	// invitation. The use is given back if the registration then fails.
+	var invitationID int64
+	if !app.emailDomainAllowed(user.Email) {
+		if input.InvitationCode == "" {
+			app.audit(r, data.AuditRegister, data.AuditFailure, nil, user.Email, "email domain not allowed")
+			v.AddError("email", "must belong to an allowed domain or come with an invitation code")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		invitationID, err = app.models.Invitations.Use(input.InvitationCode, user.Email)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.audit(r, data.AuditRegister, data.AuditFailure, nil, user.Email, "invalid invitation code")
+				v.AddError("email", "is not covered by a valid invitation code")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
 	err = app.models.Users.Insert(user)
 	if err != nil {
+		if invitationID != 0 {
+			if err := app.models.Invitations.Release(invitationID); err != nil {
+				app.logger.Println(err)
+			}
+		}
+
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
+			app.audit(r, data.AuditRegister, data.AuditFailure, nil, user.Email, "duplicate email")
 			v.AddError("email", "a user with this email address already exists")
 			app.failedValidationResponse(w, r, v.Errors)
 		default:
@@ -53,6 +113,14 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditRegister, data.AuditSuccess, user, "", "")
+
+	err = app.models.Roles.AddForUser(user.ID, data.RoleAttendee)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -98,6 +166,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			app.audit(r, data.AuditActivate, data.AuditFailure, nil, "", "invalid or expired token")
 			v.AddError("token", "invalid or expired activation token")
 			app.failedValidationResponse(w, r, v.Errors)
 		default:
@@ -106,6 +175,12 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if user.Disabled {
+		app.audit(r, data.AuditActivate, data.AuditFailure, user, "", "account disabled")
+		app.disabledAccountResponse(w, r)
+		return
+	}
+
 	user.Activated = true
 
 	err = app.models.Users.Update(user)
@@ -119,6 +194,8 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.audit(r, data.AuditActivate, data.AuditSuccess, user, "", "")
+
 	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -129,3 +206,78 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password       string `json:"password"`
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidatePasswordPlaintext(v, input.Password)
+	data.ValidateTokenPlaintext(v, input.TokenPlaintext)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetForToken(data.ScopePasswordReset, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired password reset token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.passwordPolicy.Check(v, input.Password, user.Email, user.Name); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllScopesForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Lockouts.Clear(data.LockoutAccount, strings.ToLower(user.Email))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"message": "your password was successfully reset"}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
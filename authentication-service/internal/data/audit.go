@@ -0,0 +1,211 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+)
+
+const (
+	AuditRegister       = "user.register"
+	AuditActivate       = "user.activate"
+	AuditLogin          = "login"
+	AuditTokenCreate    = "token.create"
+	AuditRoleChange     = "user.role"
+	AuditPasswordChange = "user.password"
+)
+
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry is one row of the security audit trail. UserID is zero when the
+// event could not be tied to an account, such as a login with an unknown email
+// address.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Event     string    `json:"event"`
+	Outcome   string    `json:"outcome"`
+	Detail    string    `json:"detail,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	RequestID string    `json:"request_id"`
+}
+
+// AuditFilter narrows down audit log queries. Zero values match everything.
+type AuditFilter struct {
+	UserID  int64
+	Event   string
+	Outcome string
+	From    time.Time
+	To      time.Time
+}
+
+func ValidateAuditFilter(v *validator.Validator, f AuditFilter) {
+	v.Check(f.UserID >= 0, "user_id", "must not be negative")
+
+	if f.Outcome != "" {
+		v.Check(validator.In(f.Outcome, AuditSuccess, AuditFailure), "outcome", "must be success or failure")
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() {
+		v.Check(f.From.Before(f.To), "from", "must be before to")
+	}
+}
+
+// AuditModel only ever inserts and reads. The table rejects updates and
+// deletes, so the trail cannot be rewritten through the application.
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m AuditModel) Insert(entry *AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (user_id, email, event, outcome, detail, ip, user_agent, request_id)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+
+	args := []any{
+		entry.UserID,
+		entry.Email,
+		entry.Event,
+		entry.Outcome,
+		entry.Detail,
+		entry.IP,
+		entry.UserAgent,
+		entry.RequestID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+const auditWhere = `
+		WHERE ($1 = 0 OR user_id = $1)
+		AND ($2 = '' OR event = $2)
+		AND ($3 = '' OR outcome = $3)
+		AND ($4::timestamptz IS NULL OR created_at >= $4)
+		AND ($5::timestamptz IS NULL OR created_at < $5)`
+
+func (f AuditFilter) args() []any {
+	var from, to *time.Time
+
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+
+	return []any{f.UserID, f.Event, f.Outcome, from, to}
+}
+
+func (m AuditModel) GetAll(filter AuditFilter, filters Filters) ([]*AuditEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, COALESCE(user_id, 0), email, event, outcome, detail, ip, user_agent, request_id
+		FROM audit_log
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT $6 OFFSET $7`, auditWhere, filters.sortColumn(), filters.sortDirection(), filters.sortDirection())
+
+	args := append(filter.args(), filters.limit(), filters.offset())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var entry AuditEntry
+
+		err := rows.Scan(
+			&totalRecords,
+			&entry.ID,
+			&entry.CreatedAt,
+			&entry.UserID,
+			&entry.Email,
+			&entry.Event,
+			&entry.Outcome,
+			&entry.Detail,
+			&entry.IP,
+			&entry.UserAgent,
+			&entry.RequestID,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+// ForEach calls fn for every entry matching filter, oldest first, without
+// loading them all into memory. It is meant for exports, so it is given more
+// time than the usual query timeout.
+func (m AuditModel) ForEach(filter AuditFilter, fn func(*AuditEntry) error) error {
+	query := fmt.Sprintf(`
+		SELECT id, created_at, COALESCE(user_id, 0), email, event, outcome, detail, ip, user_agent, request_id
+		FROM audit_log
+		%s
+		ORDER BY created_at ASC, id ASC`, auditWhere)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filter.args()...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry AuditEntry
+
+		err := rows.Scan(
+			&entry.ID,
+			&entry.CreatedAt,
+			&entry.UserID,
+			&entry.Email,
+			&entry.Event,
+			&entry.Outcome,
+			&entry.Detail,
+			&entry.IP,
+			&entry.UserAgent,
+			&entry.RequestID,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type DenylistModel struct {
+	DB *sql.DB
+}
+
+func (m DenylistModel) Insert(jti string, expiry time.Time) error {
+	query := `
+		INSERT INTO token_denylist (jti, expiry)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, jti, expiry)
+	return err
+}
+
+func (m DenylistModel) Contains(jti string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM token_denylist WHERE jti = $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, jti).Scan(&exists)
+	return exists, err
+}
+
+// DeleteExpired removes at most limit entries for tokens that have expired
+// anyway and returns how many were deleted.
+func (m DenylistModel) DeleteExpired(limit int) (int64, error) {
+	query := `
+		DELETE FROM token_denylist
+		WHERE jti IN (
+			SELECT jti FROM token_denylist
+			WHERE expiry < NOW()
+			LIMIT $1
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keep the tests fast; the parameters travel in the hash.
+var testArgon2idParams = argon2Params{
+	memory:      64,
+	iterations:  1,
+	parallelism: 1,
+	saltLength:  16,
+	keyLength:   32,
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hash, err := hashArgon2id("pa55word", testArgon2idParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isArgon2idHash(hash) {
+		t.Fatalf("%q is not an argon2id hash", hash)
+	}
+
+	params, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params != testArgon2idParams {
+		t.Errorf("decoded parameters %+v, want %+v", params, testArgon2idParams)
+	}
+
+	tests := []struct {
+		plaintext string
+		expected  bool
+	}{
+		{plaintext: "pa55word", expected: true},
+		{plaintext: "pa55wore", expected: false},
+		{plaintext: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		ok, err := compareArgon2id(hash, tt.plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok != tt.expected {
+			t.Errorf("compareArgon2id(%q) = %v, want %v", tt.plaintext, ok, tt.expected)
+		}
+	}
+
+	other, err := hashArgon2id("pa55word", testArgon2idParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(other) == string(hash) {
+		t.Error("two hashes of the same password share a salt")
+	}
+}
+
+func TestPasswordMatchesBcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pa55word"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := password{hash: hash}
+
+	ok, err := p.Matches("pa55word")
+	if err != nil || !ok {
+		t.Errorf("Matches = %v, %v; want true, nil", ok, err)
+	}
+
+	ok, err = p.Matches("pa55wore")
+	if err != nil || ok {
+		t.Errorf("Matches with the wrong password = %v, %v; want false, nil", ok, err)
+	}
+
+	if !p.NeedsRehash() {
+		t.Error("bcrypt hash does not need rehashing")
+	}
+}
+
+func TestPasswordSetAndMatches(t *testing.T) {
+	var p password
+
+	err := p.Set("pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := p.Matches("pa55word")
+	if err != nil || !ok {
+		t.Errorf("Matches = %v, %v; want true, nil", ok, err)
+	}
+
+	if p.NeedsRehash() {
+		t.Error("fresh hash needs rehashing")
+	}
+
+	p.SetUnusable()
+
+	ok, err = p.Matches("")
+	if err != nil || ok {
+		t.Errorf("Matches on an unusable password = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestDecodeArgon2idRejectsMalformedHashes(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "Empty", hash: ""},
+		{name: "Bcrypt", hash: "$2a$10$abcdefghijklmnopqrstuuABCDEFGHIJKLMNOPQRSTUVWXYZ01234"},
+		{name: "Other Variant", hash: "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{name: "Missing Part", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{name: "Extra Part", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + key + "$"},
+		{name: "Old Version", hash: "$argon2id$v=16$m=64,t=1,p=1$" + salt + "$" + key},
+		{name: "Bad Parameters", hash: "$argon2id$v=19$m=64;t=1;p=1$" + salt + "$" + key},
+		{name: "No Passes", hash: "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{name: "No Lanes", hash: "$argon2id$v=19$m=64,t=1,p=0$" + salt + "$" + key},
+		{name: "Too Many Lanes", hash: "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + key},
+		{name: "Bad Salt", hash: "$argon2id$v=19$m=64,t=1,p=1$!!!$" + key},
+		{name: "Bad Key", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$!!!"},
+		{name: "Empty Key", hash: "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := decodeArgon2id([]byte(tt.hash))
+			if !errors.Is(err, errInvalidArgon2Hash) {
+				t.Fatalf("decodeArgon2id = %v, want errInvalidArgon2Hash", err)
+			}
+
+			ok, err := compareArgon2id([]byte(tt.hash), "pa55word")
+			if ok || !errors.Is(err, errInvalidArgon2Hash) {
+				t.Errorf("compareArgon2id = %v, %v; want false, errInvalidArgon2Hash", ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/migrate"
+	"github.com/MohamedHossam2004/Event-Planner/user-service/migrations"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+)
+
+// testDB returns a migrated database in a schema of its own, dropped when the
+// test ends. Tests using it are skipped unless POSTGRES_TEST_URL is set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TEST_URL is not set")
+	}
+
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	schema := "users_test_" + hex.EncodeToString(suffix)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	admin := stdlib.OpenDB(*config)
+	t.Cleanup(func() { admin.Close() })
+
+	_, err = admin.ExecContext(ctx, `CREATE SCHEMA `+schema)
+	if err != nil {
+		t.Skipf("connecting to PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+	})
+
+	// Extensions such as citext stay in public, so keep it on the path.
+	config.RuntimeParams["search_path"] = schema + ",public"
+
+	db := stdlib.OpenDB(*config)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrations.Files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func insertTestUser(t *testing.T, db *sql.DB, email string) *User {
+	t.Helper()
+
+	user := &User{Name: "Jane Doe", Email: email, Activated: true}
+	user.Password.SetUnusable()
+
+	err := UserModel{DB: db}.Insert(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// ImportRow is one user from a bulk import. Line is the row's line number in
+// the uploaded file, so errors can point back at it.
+type ImportRow struct {
+	Line int
+	User *User
+	Role string
+}
+
+// ImportedUser holds the tokens a newly imported user needs to activate their
+// account and choose a password.
+type ImportedUser struct {
+	User            *User
+	ActivationToken *Token
+	PasswordToken   *Token
+}
+
+// RowError reports which line of an import a database error belongs to.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ExistingEmails returns the lower-cased subset of emails that already belong
+// to a user.
+func (m UserModel) ExistingEmails(emails []string) (map[string]bool, error) {
+	query := `SELECT email FROM users WHERE email = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, emails)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+
+	for rows.Next() {
+		var email string
+
+		err := rows.Scan(&email)
+		if err != nil {
+			return nil, err
+		}
+
+		existing[strings.ToLower(email)] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// Import creates every user in rows, gives each their role and issues the
+// activation and password tokens sent out in the welcome email, all in one
+// transaction. If any row fails nothing is kept, and the error is a *RowError
+// naming the row.
+func (m UserModel) Import(rows []ImportRow, tokenTTL time.Duration) ([]ImportedUser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	imported := make([]ImportedUser, 0, len(rows))
+
+	for _, row := range rows {
+		user := row.User
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (name, email, password_hash, activated)
+			VALUES ($1, $2, $3, false)
+			RETURNING id, created_at, version`,
+			user.Name, user.Email, user.Password.hash,
+		).Scan(&user.ID, &user.CreatedAt, &user.Version)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				err = ErrDuplicateEmail
+			}
+			return nil, &RowError{Line: row.Line, Err: err}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO users_roles (user_id, role_id)
+			SELECT $1, roles.id FROM roles WHERE roles.name = $2`,
+			user.ID, row.Role,
+		)
+		if err != nil {
+			return nil, &RowError{Line: row.Line, Err: err}
+		}
+
+		result := ImportedUser{User: user}
+
+		for _, t := range []struct {
+			scope string
+			token **Token
+		}{
+			{ScopeActivation, &result.ActivationToken},
+			{ScopePasswordReset, &result.PasswordToken},
+		} {
+			token, err := generateToken(user.ID, tokenTTL, t.scope)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO tokens (hash, user_id, expiry, scope) VALUES ($1, $2, $3, $4)`,
+				token.Hash, token.UserID, token.Expiry, token.Scope,
+			)
+			if err != nil {
+				return nil, &RowError{Line: row.Line, Err: err}
+			}
+
+			*t.token = token
+		}
+
+		imported = append(imported, result)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
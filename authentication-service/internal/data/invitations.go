@@ -0,0 +1,186 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
+)
+
+// Invitation lets someone outside the allowed email domains register. An
+// invitation may be tied to a single email address and can be used up to
+// MaxUses times before it expires.
+type Invitation struct {
+	ID        int64     `json:"id"`
+	Code      string    `json:"code,omitempty"`
+	Hash      []byte    `json:"-"`
+	Email     string    `json:"email,omitempty"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	Expiry    time.Time `json:"expiry"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InvitationModel struct {
+	DB *sql.DB
+}
+
+func ValidateInvitation(v *validator.Validator, invitation *Invitation) {
+	if invitation.Email != "" {
+		ValidateEmail(v, invitation.Email)
+	}
+
+	v.Check(invitation.MaxUses > 0, "max_uses", "must be greater than zero")
+	v.Check(invitation.MaxUses <= 1000, "max_uses", "must not be more than 1000")
+
+	v.Check(invitation.Expiry.After(time.Now()), "expiry", "must be in the future")
+	v.Check(invitation.Expiry.Before(time.Now().Add(366*24*time.Hour)), "expiry", "must not be more than a year from now")
+}
+
+func hashInvitationCode(code string) []byte {
+	hash := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hash[:]
+}
+
+func (m InvitationModel) New(invitation *Invitation) error {
+	randomBytes := make([]byte, 10)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	invitation.Code = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	invitation.Hash = hashInvitationCode(invitation.Code)
+
+	query := `
+		INSERT INTO invitations (hash, email, max_uses, expiry, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING id, created_at`
+
+	args := []any{invitation.Hash, invitation.Email, invitation.MaxUses, invitation.Expiry, invitation.CreatedBy}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&invitation.ID, &invitation.CreatedAt)
+}
+
+func (m InvitationModel) GetAll() ([]*Invitation, error) {
+	query := `
+		SELECT id, COALESCE(email, ''), max_uses, uses, expiry, COALESCE(created_by, 0), created_at
+		FROM invitations
+		ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+
+	for rows.Next() {
+		var invitation Invitation
+
+		err := rows.Scan(
+			&invitation.ID,
+			&invitation.Email,
+			&invitation.MaxUses,
+			&invitation.Uses,
+			&invitation.Expiry,
+			&invitation.CreatedBy,
+			&invitation.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, &invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// Use redeems an invitation code for the given email address and returns the
+// invitation's ID. It returns ErrRecordNotFound if the code is unknown,
+// expired, used up or issued to a different address.
+func (m InvitationModel) Use(code, email string) (int64, error) {
+	query := `
+		UPDATE invitations
+		SET uses = uses + 1
+		WHERE hash = $1
+		AND expiry > NOW()
+		AND uses < max_uses
+		AND (email IS NULL OR email = $2)
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var id int64
+
+	err := m.DB.QueryRowContext(ctx, query, hashInvitationCode(code), email).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// Release gives back a use taken by Use when the registration it was meant
+// for did not go through.
+func (m InvitationModel) Release(id int64) error {
+	query := `
+		UPDATE invitations
+		SET uses = uses - 1
+		WHERE id = $1 AND uses > 0`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (m InvitationModel) Delete(id int64) error {
+	query := `DELETE FROM invitations WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
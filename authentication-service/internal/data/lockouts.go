@@ -0,0 +1,178 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	LockoutAccount = "account"
+	LockoutIP      = "ip"
+)
+
+// Failed attempts older than lockoutWindow are forgotten. Once a subject
+// reaches its threshold it is locked for lockoutBase, doubling with every
+// further failure up to lockoutMax.
+const (
+	lockoutWindow = 24 * time.Hour
+	lockoutBase   = time.Minute
+	lockoutMax    = 24 * time.Hour
+)
+
+type Lockout struct {
+	Kind        string
+	Subject     string
+	Failures    int
+	LockedUntil time.Time
+}
+
+// RetryAfter returns how long the subject stays locked, or zero if it is not
+// locked at all.
+func (l *Lockout) RetryAfter() time.Duration {
+	if l.LockedUntil.IsZero() {
+		return 0
+	}
+
+	retryAfter := time.Until(l.LockedUntil)
+	if retryAfter < 0 {
+		return 0
+	}
+
+	return retryAfter
+}
+
+func lockoutDuration(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	duration := lockoutBase
+	for i := threshold; i < failures; i++ {
+		duration *= 2
+		if duration >= lockoutMax {
+			return lockoutMax
+		}
+	}
+
+	return duration
+}
+
+type LockoutModel struct {
+	DB *sql.DB
+}
+
+func (m LockoutModel) Get(kind, subject string) (*Lockout, error) {
+	query := `
+		SELECT kind, subject, failures, locked_until
+		FROM login_attempts
+		WHERE kind = $1 AND subject = $2`
+
+	var lockout Lockout
+	var lockedUntil sql.NullTime
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, kind, subject).Scan(
+		&lockout.Kind,
+		&lockout.Subject,
+		&lockout.Failures,
+		&lockedUntil,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	lockout.LockedUntil = lockedUntil.Time
+
+	return &lockout, nil
+}
+
+// RecordFailure counts a failed login attempt against the subject and locks it
+// out once the number of recent failures reaches threshold.
+func (m LockoutModel) RecordFailure(kind, subject string, threshold int) (*Lockout, error) {
+	query := `
+		INSERT INTO login_attempts (kind, subject, failures, last_failure)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (kind, subject) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure < NOW() - $3 * INTERVAL '1 second' THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure = NOW()
+		RETURNING failures`
+
+	lockout := &Lockout{
+		Kind:    kind,
+		Subject: subject,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, kind, subject, lockoutWindow.Seconds()).Scan(&lockout.Failures)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := lockoutDuration(lockout.Failures, threshold)
+	if duration == 0 {
+		return lockout, nil
+	}
+
+	lockout.LockedUntil = time.Now().Add(duration)
+
+	query = `
+		UPDATE login_attempts
+		SET locked_until = $3
+		WHERE kind = $1 AND subject = $2`
+
+	_, err = m.DB.ExecContext(ctx, query, kind, subject, lockout.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func (m LockoutModel) Clear(kind, subject string) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE kind = $1 AND subject = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, kind, subject)
+	return err
+}
+
+// DeleteExpired removes at most limit subjects whose failures have all been
+// forgotten and which are not locked, and returns how many were deleted.
+func (m LockoutModel) DeleteExpired(limit int) (int64, error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE (kind, subject) IN (
+			SELECT kind, subject FROM login_attempts
+			WHERE last_failure < NOW() - $1 * INTERVAL '1 second'
+			AND (locked_until IS NULL OR locked_until < NOW())
+			LIMIT $2
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, lockoutWindow.Seconds(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
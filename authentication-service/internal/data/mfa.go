@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+)
+
+const recoveryCodeCount = 10
+
+type TOTP struct {
+	UserID    int64
+	Secret    []byte
+	Confirmed bool
+	LastStep  int64
+}
+
+type MFAModel struct {
+	DB *sql.DB
+}
+
+func (m MFAModel) GetTOTP(userID int64) (*TOTP, error) {
+	query := `
+		SELECT user_id, secret, confirmed, last_step
+		FROM user_totp
+		WHERE user_id = $1`
+
+	var totp TOTP
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.Confirmed,
+		&totp.LastStep,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &totp, nil
+}
+
+// Enabled reports whether the user has a confirmed TOTP secret.
+func (m MFAModel) Enabled(userID int64) (bool, error) {
+	totp, err := m.GetTOTP(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	return totp.Confirmed, nil
+}
+
+// InsertTOTP stores a new unconfirmed secret, replacing any earlier enrolment
+// that was never confirmed. It returns ErrEditConflict if the user already has
+// 2FA enabled.
+func (m MFAModel) InsertTOTP(userID int64, secret []byte) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, confirmed, last_step)
+		VALUES ($1, $2, false, 0)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = 0
+		WHERE user_totp.confirmed = false`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// UseStep marks a TOTP step as used, confirming the secret on first use. It
+// returns ErrEditConflict if a code from the same or a later step has already
+// been accepted, which stops a captured code from being replayed.
+func (m MFAModel) UseStep(userID, step int64) error {
+	query := `
+		UPDATE user_totp
+		SET last_step = $2, confirmed = true
+		WHERE user_id = $1 AND last_step < $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+func (m MFAModel) DeleteTOTP(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	return err
+}
+
+// NewRecoveryCodes replaces the user's recovery codes and returns the new
+// plaintext codes. Only their hashes are stored.
+func (m MFAModel) NewRecoveryCodes(userID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hash := hashRecoveryCode(code)
+
+		_, err = m.DB.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, hash) VALUES ($1, $2)`, userID, hash[:])
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = code
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode consumes a recovery code and reports whether it was valid.
+func (m MFAModel) UseRecoveryCode(userID int64, code string) (bool, error) {
+	hash := hashRecoveryCode(code)
+
+	query := `DELETE FROM recovery_codes WHERE user_id = $1 AND hash = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, hash[:])
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 10)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)), nil
+}
+
+// hashRecoveryCode hashes a recovery code the way it is stored, ignoring the
+// case and surrounding spaces of codes typed in by the user.
+func hashRecoveryCode(code string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+}
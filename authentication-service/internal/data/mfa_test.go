@@ -0,0 +1,129 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	seen := map[string]bool{}
+
+	for range 100 {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(code) != 16 || code != strings.ToLower(code) {
+			t.Fatalf("unexpected recovery code %q", code)
+		}
+
+		if seen[code] {
+			t.Fatalf("recovery code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	hash := hashRecoveryCode("abcdefghijklmnop")
+
+	for _, typed := range []string{"ABCDEFGHIJKLMNOP", "  abcdefghijklmnop\n", "AbCdEfGhIjKlMnOp "} {
+		if hashRecoveryCode(typed) != hash {
+			t.Errorf("hash of %q differs from the stored code's", typed)
+		}
+	}
+
+	if hashRecoveryCode("abcdefghijklmnoq") == hash {
+		t.Error("different codes hash the same")
+	}
+}
+
+func TestUseStep(t *testing.T) {
+	db := testDB(t)
+	mfa := MFAModel{DB: db}
+	user := insertTestUser(t, db, "jane@example.com")
+
+	err := mfa.InsertTOTP(user.ID, []byte("12345678901234567890"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mfa.UseStep(user.ID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	totp, err := mfa.GetTOTP(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !totp.Confirmed || totp.LastStep != 100 {
+		t.Fatalf("got confirmed %v and last step %d, want true and 100", totp.Confirmed, totp.LastStep)
+	}
+
+	for _, step := range []int64{100, 99} {
+		err = mfa.UseStep(user.ID, step)
+		if !errors.Is(err, ErrEditConflict) {
+			t.Errorf("UseStep(%d) = %v, want ErrEditConflict", step, err)
+		}
+	}
+
+	err = mfa.UseStep(user.ID, 101)
+	if err != nil {
+		t.Errorf("UseStep(101) = %v, want nil", err)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	db := testDB(t)
+	mfa := MFAModel{DB: db}
+	jane := insertTestUser(t, db, "jane@example.com")
+	john := insertTestUser(t, db, "john@example.com")
+
+	codes, err := mfa.NewRecoveryCodes(jane.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	use := func(userID int64, code string) bool {
+		t.Helper()
+
+		ok, err := mfa.UseRecoveryCode(userID, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if use(john.ID, codes[0]) {
+		t.Error("another user's recovery code was accepted")
+	}
+
+	if !use(jane.ID, " "+strings.ToUpper(codes[0])+" ") {
+		t.Error("recovery code typed in upper case was rejected")
+	}
+
+	if use(jane.ID, codes[0]) {
+		t.Error("recovery code was accepted twice")
+	}
+
+	replaced, err := mfa.NewRecoveryCodes(jane.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if use(jane.ID, codes[1]) {
+		t.Error("replaced recovery code was accepted")
+	}
+
+	if !use(jane.ID, replaced[0]) {
+		t.Error("new recovery code was rejected")
+	}
+}
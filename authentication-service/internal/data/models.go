@@ -8,13 +8,33 @@ import (
 const dbTimeout = 3 * time.Second
 
 type Models struct {
-	Tokens TokenModel
-	Users  UserModel
+	APIKeys     APIKeyModel
+	Audit       AuditModel
+	Denylist    DenylistModel
+	Invitations InvitationModel
+	Lockouts    LockoutModel
+	MFA         MFAModel
+	OIDCLogins  OIDCLoginModel
+	Permissions PermissionModel
+	Roles       RoleModel
+	SigningKeys SigningKeyModel
+	Tokens      TokenModel
+	Users       UserModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Tokens: TokenModel{DB: db},
-		Users:  UserModel{DB: db},
+		APIKeys:     APIKeyModel{DB: db},
+		Audit:       AuditModel{DB: db},
+		Denylist:    DenylistModel{DB: db},
+		Invitations: InvitationModel{DB: db},
+		Lockouts:    LockoutModel{DB: db},
+		MFA:         MFAModel{DB: db},
+		OIDCLogins:  OIDCLoginModel{DB: db},
+		Permissions: PermissionModel{DB: db},
+		Roles:       RoleModel{DB: db},
+		SigningKeys: SigningKeyModel{DB: db},
+		Tokens:      TokenModel{DB: db},
+		Users:       UserModel{DB: db},
 	}
 }
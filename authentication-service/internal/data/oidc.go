@@ -0,0 +1,97 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// OIDCLogin is a login that has been sent to the OpenID provider and not yet
+// come back. It is looked up by the state parameter together with Binding, a
+// secret that stays with the client that started the login and never passes
+// through the provider. Only hashes of the two are stored.
+type OIDCLogin struct {
+	State        string
+	Binding      string
+	Nonce        string
+	CodeVerifier string
+	Expiry       time.Time
+}
+
+type OIDCLoginModel struct {
+	DB *sql.DB
+}
+
+func (m OIDCLoginModel) Insert(login *OIDCLogin) error {
+	stateHash := sha256.Sum256([]byte(login.State))
+	bindingHash := sha256.Sum256([]byte(login.Binding))
+
+	query := `
+		INSERT INTO oidc_logins (state_hash, binding_hash, nonce, code_verifier, expiry)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, stateHash[:], bindingHash[:], login.Nonce, login.CodeVerifier, login.Expiry)
+	return err
+}
+
+// Consume deletes the pending login for state and binding and returns it, so
+// each state can only be redeemed once, and only by the client that started
+// the login. Unknown and expired states, and states sent with another binding,
+// give ErrRecordNotFound.
+func (m OIDCLoginModel) Consume(state, binding string) (*OIDCLogin, error) {
+	stateHash := sha256.Sum256([]byte(state))
+	bindingHash := sha256.Sum256([]byte(binding))
+
+	query := `
+		DELETE FROM oidc_logins
+		WHERE state_hash = $1 AND binding_hash = $2
+		RETURNING nonce, code_verifier, expiry`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	login := OIDCLogin{State: state, Binding: binding}
+
+	err := m.DB.QueryRowContext(ctx, query, stateHash[:], bindingHash[:]).Scan(&login.Nonce, &login.CodeVerifier, &login.Expiry)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if login.Expiry.Before(time.Now()) {
+		return nil, ErrRecordNotFound
+	}
+
+	return &login, nil
+}
+
+// DeleteExpired removes at most limit logins that were never completed and
+// returns how many were deleted.
+func (m OIDCLoginModel) DeleteExpired(limit int) (int64, error) {
+	query := `
+		DELETE FROM oidc_logins
+		WHERE state_hash IN (
+			SELECT state_hash FROM oidc_logins
+			WHERE expiry < NOW()
+			LIMIT $1
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,239 @@
+package data
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// SigningKey is an Ed25519 key pair used to sign authentication tokens. Keys
+// are identified in the JWT header by ID and stay published in the JWKS until
+// ExpiresAt, so tokens signed shortly before a rotation remain verifiable.
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// SigningKeyModel stores the private keys encrypted with AES-256-GCM under
+// EncryptionKey, so a copy of the database alone is not enough to forge
+// tokens. Each key's ID is bound to its ciphertext as additional data.
+type SigningKeyModel struct {
+	DB            *sql.DB
+	EncryptionKey []byte
+}
+
+func (k *SigningKey) PublicKey() ed25519.PublicKey {
+	return k.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+func generateSigningKey(ttl time.Duration) (*SigningKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+	_, err = rand.Read(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		ID:         hex.EncodeToString(kid),
+		PrivateKey: privateKey,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(ttl),
+	}
+
+	return key, nil
+}
+
+func (m SigningKeyModel) New(ttl time.Duration) (*SigningKey, error) {
+	key, err := generateSigningKey(ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(key)
+	return key, err
+}
+
+func (m SigningKeyModel) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a key's seed, prefixing the ciphertext with its nonce.
+func (m SigningKeyModel) seal(kid string, seed []byte) ([]byte, error) {
+	aead, err := m.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, seed, []byte(kid)), nil
+}
+
+func (m SigningKeyModel) open(kid string, ciphertext []byte) ([]byte, error) {
+	aead, err := m.aead()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("signing key %s is malformed", kid)
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	seed, err := aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s cannot be decrypted: %w", kid, err)
+	}
+
+	return seed, nil
+}
+
+func (m SigningKeyModel) Insert(key *SigningKey) error {
+	query := `
+		INSERT INTO signing_keys (kid, private_key, encrypted, created_at, expires_at)
+		VALUES ($1, $2, true, $3, $4)`
+
+	ciphertext, err := m.seal(key.ID, key.PrivateKey.Seed())
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{key.ID, ciphertext, key.CreatedAt, key.ExpiresAt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// EncryptPlaintext encrypts the keys stored before encryption was introduced
+// and returns how many there were.
+func (m SigningKeyModel) EncryptPlaintext() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT kid, private_key
+		FROM signing_keys
+		WHERE encrypted = false
+		FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+
+	seeds := map[string][]byte{}
+
+	for rows.Next() {
+		var kid string
+		var seed []byte
+
+		err := rows.Scan(&kid, &seed)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		seeds[kid] = seed
+	}
+
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for kid, seed := range seeds {
+		ciphertext, err := m.seal(kid, seed)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE signing_keys
+			SET private_key = $1, encrypted = true
+			WHERE kid = $2`, ciphertext, kid)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(seeds), tx.Commit()
+}
+
+// GetAllActive returns the keys that have not expired yet, newest first.
+func (m SigningKeyModel) GetAllActive() ([]*SigningKey, error) {
+	query := `
+		SELECT kid, private_key, encrypted, created_at, expires_at
+		FROM signing_keys
+		WHERE expires_at > $1
+		ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*SigningKey{}
+
+	for rows.Next() {
+		var key SigningKey
+		var ciphertext []byte
+		var encrypted bool
+
+		err := rows.Scan(&key.ID, &ciphertext, &encrypted, &key.CreatedAt, &key.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if !encrypted {
+			return nil, fmt.Errorf("signing key %s is stored unencrypted", key.ID)
+		}
+
+		seed, err := m.open(key.ID, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+
+		key.PrivateKey = ed25519.NewKeyFromSeed(seed)
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -1,10 +1,12 @@
 package data
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/user-service/internal/validator"
@@ -26,6 +28,7 @@ type User struct {
 	Password  password  `json:"-"`
 	IsAdmin   bool      `json:"is_admin"`
 	Activated bool      `json:"activated"`
+	Disabled  bool      `json:"disabled"`
 	Version   int       `json:"version"`
 }
 
@@ -45,7 +48,7 @@ func (u *User) IsAnonymous() bool {
 }
 
 func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
+	hash, err := hashArgon2id(plaintextPassword, argon2idParams)
 	if err != nil {
 		return err
 	}
@@ -56,7 +59,34 @@ func (p *password) Set(plaintextPassword string) error {
 	return nil
 }
 
+// unusablePasswordHash marks an account that has no password yet, such as one
+// created by a bulk import. It is neither an argon2id nor a bcrypt hash, so
+// nothing can ever match it.
+var unusablePasswordHash = []byte("!")
+
+// SetUnusable leaves the user without a password. They have to set one through
+// the password reset flow before they can log in with it.
+func (p *password) SetUnusable() {
+	p.plaintext = nil
+	p.hash = unusablePasswordHash
+}
+
+// IsUnusable reports whether the user has yet to set a password.
+func (p *password) IsUnusable() bool {
+	return bytes.Equal(p.hash, unusablePasswordHash)
+}
+
+// Matches checks a password against the stored hash. Hashes made with bcrypt,
+// which was used before argon2id, are still accepted.
 func (p *password) Matches(plaintextPassword string) (bool, error) {
+	if bytes.Equal(p.hash, unusablePasswordHash) {
+		return false, nil
+	}
+
+	if isArgon2idHash(p.hash) {
+		return compareArgon2id(p.hash, plaintextPassword)
+	}
+
 	err := bcrypt.CompareHashAndPassword(p.hash, []byte(plaintextPassword))
 	if err != nil {
 		switch {
@@ -69,6 +99,22 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 	return true, nil
 }
 
+// NeedsRehash reports whether the stored hash is a bcrypt hash or an argon2id
+// hash with parameters other than the current ones. Callers holding the
+// plaintext, such as a successful login, should then Set it again.
+func (p *password) NeedsRehash() bool {
+	if bytes.Equal(p.hash, unusablePasswordHash) {
+		return false
+	}
+
+	if !isArgon2idHash(p.hash) {
+		return true
+	}
+
+	params, _, _, err := decodeArgon2id(p.hash)
+	return err != nil || params != argon2idParams
+}
+
 func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(email != "", "email", "must be provided")
 	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
@@ -77,7 +123,7 @@ func ValidateEmail(v *validator.Validator, email string) {
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(password != "", "password", "must be provided")
 	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.Check(len(password) <= 1024, "password", "must not be more than 1024 bytes long")
 }
 
 func ValidateUser(v *validator.Validator, user *User) {
@@ -119,7 +165,7 @@ func (m UserModel) Insert(user *User) error {
 
 func (m UserModel) Get(id int64) (*User, error) {
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version
+		SELECT id, created_at, name, email, password_hash, activated, disabled, version, isadmin
 		FROM users
 		WHERE id = $1`
 
@@ -135,7 +181,9 @@ func (m UserModel) Get(id int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Disabled,
 		&user.Version,
+		&user.IsAdmin,
 	)
 
 	if err != nil {
@@ -152,7 +200,7 @@ func (m UserModel) Get(id int64) (*User, error) {
 
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, created_at, name, email, password_hash, activated, version, isadmin
+		SELECT id, created_at, name, email, password_hash, activated, disabled, version, isadmin
 		FROM users
 		WHERE email = $1`
 
@@ -168,6 +216,7 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Disabled,
 		&user.Version,
 		&user.IsAdmin,
 	)
@@ -184,11 +233,64 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// GetAll returns a page of users whose name or email contains the search
+// string, along with pagination metadata for the full result set.
+func (m UserModel) GetAll(search string, filters Filters) ([]*User, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, name, email, password_hash, activated, disabled, version, isadmin
+		FROM users
+		WHERE ($1 = '' OR name ILIKE '%%' || $1 || '%%' OR email ILIKE '%%' || $1 || '%%')
+		ORDER BY %s %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, search, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	users := []*User{}
+
+	for rows.Next() {
+		var user User
+
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Password.hash,
+			&user.Activated,
+			&user.Disabled,
+			&user.Version,
+			&user.IsAdmin,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return users, metadata, nil
+}
+
 func (m UserModel) Update(user *User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-		WHERE id = $5 AND version = $6
+		SET name = $1, email = $2, password_hash = $3, activated = $4, disabled = $5, isadmin = $6, version = version + 1
+		WHERE id = $7 AND version = $8
 		RETURNING version`
 
 	args := []interface{}{
@@ -196,6 +298,8 @@ func (m UserModel) Update(user *User) error {
 		user.Email,
 		user.Password.hash,
 		user.Activated,
+		user.Disabled,
+		user.IsAdmin,
 		user.ID,
 		user.Version,
 	}
@@ -205,8 +309,51 @@ func (m UserModel) Update(user *User) error {
 
 	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Version)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return ErrDuplicateEmail
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetPendingEmail records an address the user has asked to switch to. It only
+// replaces users.email once ConfirmPendingEmail is called with the token sent
+// to that address.
+func (m UserModel) SetPendingEmail(id int64, email string) error {
+	query := `
+		UPDATE users
+		SET pending_email = $1
+		WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, email, id)
+	return err
+}
+
+func (m UserModel) ConfirmPendingEmail(user *User) error {
+	query := `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, version = version + 1
+		WHERE id = $1 AND version = $2 AND pending_email IS NOT NULL
+		RETURNING email, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, user.ID, user.Version).Scan(&user.Email, &user.Version)
+	if err != nil {
+		var pgErr *pgconn.PgError
 		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
 			return ErrDuplicateEmail
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
@@ -222,7 +369,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.disabled, users.version, users.isadmin
 		FROM users
 		INNER JOIN tokens
 		ON users.id = tokens.user_id
@@ -244,7 +391,9 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Disabled,
 		&user.Version,
+		&user.IsAdmin,
 	)
 	if err != nil {
 		switch {
@@ -0,0 +1,359 @@
+// Package oidc implements the relying-party side of the OpenID Connect
+// authorization code flow with PKCE: provider discovery, building the
+// authorization URL, exchanging the code and validating the ID token.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+var (
+	ErrInvalidIDToken    = errors.New("oidc: invalid ID token")
+	ErrExchangeFailed    = errors.New("oidc: code exchange failed")
+	errDiscoveryMismatch = errors.New("oidc: discovered issuer does not match the configured one")
+)
+
+// clockSkew is how far the provider's clock may be ahead of or behind ours.
+const clockSkew = time.Minute
+
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Identity is what the provider vouches for about the user who logged in.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID provider. Discovery is done on first use rather
+// than at startup, so the service still starts while the provider is down. The
+// provider's keys are refetched when an ID token names a key ID we have not
+// seen, which is what happens after the provider rotates its keys.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      *jwt.KeyRegister
+	kids      map[string]bool
+	fetchedAt time.Time
+}
+
+func New(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// RandomString returns a URL-safe random string for use as a state, nonce or
+// PKCE code verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge for a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL to send the user to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("redirect_uri", p.config.RedirectURL)
+	q.Set("scope", strings.Join(p.config.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", CodeChallenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity from the
+// validated ID token. nonce must be the value sent with the authorization
+// request.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+		return nil, fmt.Errorf("%w: token endpoint returned status %d", ErrExchangeFailed, res.StatusCode)
+	}
+
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrExchangeFailed)
+	}
+
+	return p.VerifyIDToken(ctx, body.IDToken, nonce)
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, lifetime and
+// nonce as required by OpenID Connect Core, section 3.1.3.7.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := p.checkSignature(ctx, d, []byte(rawToken))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+
+	if claims.Issuer != d.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+
+	if !claims.AcceptAudience(p.config.ClientID) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+
+	if len(claims.Audiences) > 1 {
+		if azp, _ := claims.String("azp"); azp != p.config.ClientID {
+			return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+		}
+	}
+
+	now := time.Now()
+	if claims.Expires == nil || claims.Expires.Time().Before(now.Add(-clockSkew)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	}
+
+	if claims.NotBefore != nil && claims.NotBefore.Time().After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: not yet valid", ErrInvalidIDToken)
+	}
+
+	if got, _ := claims.String("nonce"); nonce == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	identity := &Identity{Subject: claims.Subject}
+	identity.Email, _ = claims.String("email")
+	identity.Name, _ = claims.String("name")
+
+	// Some providers send email_verified as a string.
+	switch v := claims.Set["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified, _ = strconv.ParseBool(v)
+	}
+
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) checkSignature(ctx context.Context, d *discovery, token []byte) (*jwt.Claims, error) {
+	unverified, err := jwt.ParseWithoutCheck(token)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	keys := p.keys
+	known := p.kids[unverified.KeyID]
+	recent := time.Since(p.fetchedAt) < 10*time.Second
+	p.mu.Unlock()
+
+	if keys == nil || (!known && !recent) {
+		keys, err = p.fetchKeys(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keys.Check(token)
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, d *discovery) (*jwt.KeyRegister, error) {
+	body, err := p.get(ctx, d.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	err = json.Unmarshal(body, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	// ID tokens may also be signed with the client secret (HS256), but never
+	// with an empty one, which anybody could forge.
+	keys := &jwt.KeyRegister{}
+	if p.config.ClientSecret != "" {
+		keys.Secrets = [][]byte{[]byte(p.config.ClientSecret)}
+	}
+	kids := map[string]bool{}
+
+	// Keys of a type we cannot use are skipped rather than failing the whole
+	// set.
+	for _, raw := range set.Keys {
+		var header struct {
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+		}
+		if json.Unmarshal(raw, &header) != nil || header.Use == "enc" {
+			continue
+		}
+
+		if _, err := keys.LoadJWK(raw); err == nil {
+			kids[header.Kid] = true
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.kids = kids
+	p.fetchedAt = time.Now()
+	p.mu.Unlock()
+
+	return keys, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	d := p.discovery
+	p.mu.Unlock()
+
+	if d != nil {
+		return d, nil
+	}
+
+	body, err := p.get(ctx, strings.TrimSuffix(p.config.IssuerURL, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+
+	d = &discovery{}
+	err = json.Unmarshal(body, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(p.config.IssuerURL, "/") {
+		return nil, errDiscoveryMismatch
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	p.discovery = d
+	p.mu.Unlock()
+
+	return d, nil
+}
+
+func (p *Provider) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: GET %s returned status %d", url, res.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
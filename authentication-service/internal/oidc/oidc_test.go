@@ -0,0 +1,296 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+// mockIdP is a minimal OpenID provider that hands out one authorization code
+// per /authorize request and signs ID tokens with an RSA key.
+type mockIdP struct {
+	*httptest.Server
+
+	key    *rsa.PrivateKey
+	claims func(*jwt.Claims)
+
+	// signingKey signs ID tokens; it is key unless a test swaps it.
+	signingKey *rsa.PrivateKey
+
+	// code, challenge and nonce are from the last /authorize request.
+	code      string
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdP{key: key, signingKey: key, claims: func(*jwt.Claims) {}}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "PKCE required", http.StatusBadRequest)
+			return
+		}
+
+		idp.code = "code-" + q.Get("state")
+		idp.challenge = q.Get("code_challenge")
+		idp.nonce = q.Get("nonce")
+
+		target, _ := url.Parse(q.Get("redirect_uri"))
+		target.RawQuery = url.Values{"code": {idp.code}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "event-hub" || secret != "s3cret" {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if r.PostFormValue("code") != idp.code || CodeChallenge(r.PostFormValue("code_verifier")) != idp.challenge {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		var claims jwt.Claims
+		claims.KeyID = "test-key"
+		claims.Issuer = idp.URL
+		claims.Subject = "248289761001"
+		claims.Audiences = []string{"event-hub"}
+		claims.Issued = jwt.NewNumericTime(time.Now())
+		claims.Expires = jwt.NewNumericTime(time.Now().Add(5 * time.Minute))
+		claims.Set = map[string]any{
+			"nonce":          idp.nonce,
+			"email":          "ada@example.com",
+			"email_verified": true,
+			"name":           "Ada Lovelace",
+		}
+		idp.claims(&claims)
+
+		token, err := claims.RSASign(jwt.RS256, idp.signingKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "opaque",
+			"token_type":   "Bearer",
+			"id_token":     string(token),
+		})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+// login runs the browser's part of the flow and returns the code and state
+// the provider redirected back with.
+func (idp *mockIdP) login(t *testing.T, p *Provider, state, nonce, verifier string) (string, string) {
+	t.Helper()
+
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned status %d", res.StatusCode)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+
+	newProvider := func() *Provider {
+		return New(Config{
+			IssuerURL:    idp.URL,
+			ClientID:     "event-hub",
+			ClientSecret: "s3cret",
+			RedirectURL:  "http://localhost/oidc/callback",
+		})
+	}
+
+	t.Run("Valid Login", func(t *testing.T) {
+		idp.claims = func(*jwt.Claims) {}
+		p := newProvider()
+
+		code, state := idp.login(t, p, "state-1", "nonce-1", "verifier-1")
+		if state != "state-1" {
+			t.Fatalf("got state %q", state)
+		}
+
+		identity, err := p.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Identity{Subject: "248289761001", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"}
+		if *identity != want {
+			t.Errorf("got identity %+v, want %+v", *identity, want)
+		}
+	})
+
+	t.Run("Email Verified As String", func(t *testing.T) {
+		idp.claims = func(c *jwt.Claims) { c.Set["email_verified"] = "true" }
+		p := newProvider()
+
+		code, _ := idp.login(t, p, "state-2", "nonce-2", "verifier-2")
+
+		identity, err := p.Exchange(context.Background(), code, "verifier-2", "nonce-2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !identity.EmailVerified {
+			t.Error("email_verified \"true\" not accepted")
+		}
+	})
+
+	tests := []struct {
+		name     string
+		claims   func(*jwt.Claims)
+		foreign  bool
+		verifier string
+		nonce    string
+		wantErr  error
+	}{
+		{
+			name:     "Wrong Code Verifier",
+			claims:   func(*jwt.Claims) {},
+			verifier: "someone-else",
+			nonce:    "nonce-3",
+			wantErr:  ErrExchangeFailed,
+		},
+		{
+			name:     "Nonce Mismatch",
+			claims:   func(*jwt.Claims) {},
+			verifier: "verifier-3",
+			nonce:    "replayed",
+			wantErr:  ErrInvalidIDToken,
+		},
+		{
+			name:     "Wrong Audience",
+			claims:   func(c *jwt.Claims) { c.Audiences = []string{"another-app"} },
+			verifier: "verifier-3",
+			nonce:    "nonce-3",
+			wantErr:  ErrInvalidIDToken,
+		},
+		{
+			name:     "Wrong Issuer",
+			claims:   func(c *jwt.Claims) { c.Issuer = "https://evil.example.com" },
+			verifier: "verifier-3",
+			nonce:    "nonce-3",
+			wantErr:  ErrInvalidIDToken,
+		},
+		{
+			name:     "Expired",
+			claims:   func(c *jwt.Claims) { c.Expires = jwt.NewNumericTime(time.Now().Add(-time.Hour)) },
+			verifier: "verifier-3",
+			nonce:    "nonce-3",
+			wantErr:  ErrInvalidIDToken,
+		},
+		{
+			name:     "Unpublished Signing Key",
+			claims:   func(*jwt.Claims) {},
+			foreign:  true,
+			verifier: "verifier-3",
+			nonce:    "nonce-3",
+			wantErr:  ErrInvalidIDToken,
+		},
+	}
+
+	foreignKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.claims = tt.claims
+			idp.signingKey = idp.key
+			if tt.foreign {
+				idp.signingKey = foreignKey
+			}
+			p := newProvider()
+
+			code, _ := idp.login(t, p, "state-3", "nonce-3", "verifier-3")
+
+			_, err := p.Exchange(context.Background(), code, tt.verifier, tt.nonce)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+
+	p := New(Config{IssuerURL: idp.URL + "/tenant", ClientID: "event-hub", RedirectURL: "http://localhost/cb"})
+
+	_, err := p.AuthCodeURL(context.Background(), "s", "n", "v")
+	if err == nil {
+		t.Error("expected an error for a provider that is not at the configured issuer")
+	}
+}
@@ -0,0 +1,65 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 Appendix B test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// The RFC lists 8-digit codes; a 6-digit code is their last six digits.
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tt := range tests {
+		code := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if code != tt.expected {
+			t.Errorf("Code at %d = %q, want %q", tt.unix, code, tt.expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := Code(rfcSecret, Step(now))
+
+	tests := []struct {
+		name  string
+		code  string
+		at    time.Time
+		valid bool
+	}{
+		{name: "Current Step", code: code, at: now, valid: true},
+		{name: "One Step Early", code: code, at: now.Add(-Period), valid: true},
+		{name: "One Step Late", code: code, at: now.Add(Period), valid: true},
+		{name: "Two Steps Early", code: code, at: now.Add(-2 * Period), valid: false},
+		{name: "Two Steps Late", code: code, at: now.Add(2 * Period), valid: false},
+		{name: "Wrong Code", code: "000000", at: now, valid: false},
+		{name: "Too Short", code: code[:Digits-1], at: now, valid: false},
+		{name: "Too Long", code: code + "0", at: now, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.at)
+			if ok != tt.valid {
+				t.Fatalf("Validate = %v, want %v", ok, tt.valid)
+			}
+
+			if ok && step != Step(now) {
+				t.Errorf("Validate step = %d, want %d", step, Step(now))
+			}
+		})
+	}
+}
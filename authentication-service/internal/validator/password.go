@@ -0,0 +1,158 @@
+package validator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// common_passwords.txt.gz holds lower-cased passwords that are common or have
+// turned up in public breaches, one per line. It is taken from the zxcvbn
+// password frequency list (MIT licensed).
+//
+//go:embed common_passwords.txt.gz
+var commonPasswordsGz []byte
+
+// PasswordPolicy rejects passwords that are easy to guess: those with too
+// little entropy, those built from the user's own name or email address and
+// those on the bundled list of common and breached passwords. The list is
+// checked offline so no password ever leaves the service.
+type PasswordPolicy struct {
+	MinEntropy float64
+	common     map[string]struct{}
+}
+
+func NewPasswordPolicy(minEntropy float64) (*PasswordPolicy, error) {
+	r, err := gzip.NewReader(bytes.NewReader(commonPasswordsGz))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	policy := &PasswordPolicy{
+		MinEntropy: minEntropy,
+		common:     make(map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			policy.common[line] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Check records a "password" error on v if password breaks the policy.
+// personal holds values such as the user's name and email address that must
+// not appear in the password.
+func (p *PasswordPolicy) Check(v *Validator, password string, personal ...string) {
+	lower := strings.ToLower(password)
+
+	_, common := p.common[lower]
+	v.Check(!common, "password", "is too common, please choose a different one")
+
+	for _, value := range personal {
+		for _, part := range personalParts(value) {
+			v.Check(!strings.Contains(lower, part), "password", "must not contain your name or email address")
+		}
+	}
+
+	v.Check(PasswordEntropy(password) >= p.MinEntropy, "password", "is too easy to guess, try a longer password or mix in other kinds of characters")
+}
+
+// personalParts splits a name or email address into the pieces worth checking
+// for. Pieces shorter than three characters would reject too many passwords.
+func personalParts(value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+
+	parts := []string{value}
+
+	local, _, isEmail := strings.Cut(value, "@")
+	if isEmail {
+		parts = append(parts, local)
+		value = local
+	}
+
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	parts = append(parts, fields...)
+
+	result := parts[:0]
+	for _, part := range parts {
+		if len(part) >= 3 {
+			result = append(result, part)
+		}
+	}
+
+	return result
+}
+
+// PasswordEntropy estimates the entropy of password in bits from the size of
+// the character classes it draws on. Characters that repeat or continue a run
+// from the previous character ("aaaa", "1234", "abcd") only count for half.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+
+	length := 0.0
+	var previous rune
+
+	for i, r := range []rune(password) {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		if i > 0 && (r == previous || r == previous+1 || r == previous-1) {
+			length += 0.5
+		} else {
+			length++
+		}
+
+		previous = r
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return length * math.Log2(float64(pool))
+}
@@ -54,5 +54,5 @@ func Unique(values []string) bool {
 		}
 		uniqueValues[value] = true
 	}
-	return false
+	return true
 }
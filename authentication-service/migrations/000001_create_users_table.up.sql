@@ -0,0 +1,13 @@
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    email citext NOT NULL,
+    password_hash bytea NOT NULL,
+    activated bool NOT NULL DEFAULT false,
+    isadmin bool NOT NULL DEFAULT false,
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT users_email_key UNIQUE (email)
+);
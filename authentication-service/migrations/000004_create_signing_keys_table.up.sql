@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid text PRIMARY KEY,
+    private_key bytea NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp(0) with time zone NOT NULL
+);
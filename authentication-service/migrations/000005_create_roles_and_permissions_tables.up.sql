@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id bigserial PRIMARY KEY,
+    code text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name)
+VALUES ('admin'), ('organizer'), ('usher'), ('attendee')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (code)
+VALUES ('users:manage'), ('events:read'), ('events:write'), ('events:checkin')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO roles_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE (roles.name = 'admin')
+   OR (roles.name = 'organizer' AND permissions.code IN ('events:read', 'events:write'))
+   OR (roles.name = 'usher' AND permissions.code IN ('events:read', 'events:checkin'))
+   OR (roles.name = 'attendee' AND permissions.code = 'events:read')
+ON CONFLICT DO NOTHING;
+
+-- Existing accounts predate roles, so give them the ones they would have been
+-- assigned at registration.
+INSERT INTO users_roles (user_id, role_id)
+SELECT users.id, roles.id
+FROM users, roles
+WHERE roles.name = 'attendee' OR (roles.name = 'admin' AND users.isadmin)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id bigserial PRIMARY KEY,
+    hash bytea NOT NULL UNIQUE,
+    email citext,
+    max_uses integer NOT NULL DEFAULT 1,
+    uses integer NOT NULL DEFAULT 0,
+    expiry timestamp(0) with time zone NOT NULL,
+    created_by bigint REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT invitations_uses_check CHECK (uses <= max_uses)
+);
@@ -0,0 +1,4 @@
+-- Encrypted keys would be read back as seeds without the column, so they are
+-- dropped and a fresh key is generated on the next start.
+DELETE FROM signing_keys WHERE encrypted = true;
+ALTER TABLE signing_keys DROP COLUMN IF EXISTS encrypted;
@@ -0,0 +1,4 @@
+-- Pending logins only last minutes and cannot be redeemed without a binding,
+-- so they are dropped rather than migrated.
+DELETE FROM oidc_logins;
+ALTER TABLE oidc_logins ADD COLUMN IF NOT EXISTS binding_hash bytea NOT NULL;
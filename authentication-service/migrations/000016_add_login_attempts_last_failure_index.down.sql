@@ -0,0 +1 @@
+DROP INDEX IF EXISTS login_attempts_last_failure_idx;
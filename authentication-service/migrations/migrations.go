@@ -0,0 +1,8 @@
+// Package migrations embeds the versioned SQL migrations for the user
+// database so they ship inside the service binary.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
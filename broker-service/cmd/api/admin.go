@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/admin/users?"+r.URL.RawQuery, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) showUserHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("GET", fmt.Sprintf("http://authentication-service/v1/admin/users/%s", idStr), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) updateUserStatusHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("PATCH", fmt.Sprintf("http://authentication-service/v1/admin/users/%s", idStr), r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("POST", fmt.Sprintf("http://authentication-service/v1/admin/users/%s/activation", idStr), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) clearLockoutHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("http://authentication-service/v1/admin/users/%s/lockout", idStr), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+// userRoleHandler grants (PUT) or revokes (DELETE) a role, passing the method
+// on unchanged.
+func (app *application) userRoleHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	role := chi.URLParam(r, "role")
+	if idStr == "" || role == "" {
+		app.badRequestResponse(w, r, errors.New("missing id or role"))
+		return
+	}
+
+	request, err := http.NewRequest(r.Method, fmt.Sprintf("http://authentication-service/v1/admin/users/%s/roles/%s", idStr, url.PathEscape(role)), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) cleanupTokensHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/admin/tokens/cleanup", nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) listInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/admin/invitations", nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) createInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/admin/invitations", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) deleteInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("http://authentication-service/v1/admin/invitations/%s", idStr), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) listAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/admin/audit-log?"+r.URL.RawQuery, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+// exportAuditLogHandler streams the CSV export straight through, since it is
+// not JSON and can be large. Errors still come back as JSON and are handled
+// like any other response.
+func (app *application) exportAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/admin/audit-log/export?"+r.URL.RawQuery, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		w.Header().Set("Content-Type", response.Header.Get("Content-Type"))
+		w.Header().Set("Content-Disposition", response.Header.Get("Content-Disposition"))
+		w.WriteHeader(http.StatusOK)
+
+		_, err = io.Copy(w, response.Body)
+		if err != nil {
+			app.logError(r, err)
+		}
+		return
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) importUsersHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/admin/users/import?"+r.URL.RawQuery, r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
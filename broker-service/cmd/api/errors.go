@@ -82,3 +82,8 @@ func (app *application) onlyUsersResponse(w http.ResponseWriter, r *http.Request
 	message := "only authenticated users can access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (app *application) lockedOutResponse(w http.ResponseWriter, r *http.Request) {
+	message := "too many failed login attempts, please try again later"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
@@ -11,7 +11,39 @@ import (
 )
 
 func (app *application) getAllEventsHandler(w http.ResponseWriter, r *http.Request) {
-	request, err := http.NewRequest("GET", "http://event-service/v1/events", nil)
+	request, err := http.NewRequest("GET", "http://event-service/v1/events/?"+r.URL.RawQuery, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) searchEventsHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://event-service/v1/events/search?"+r.URL.RawQuery, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -119,7 +151,7 @@ func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	request, err := http.NewRequest("PUT", fmt.Sprintf("http://event-service/v1/events/%s", idStr), r.Body)
+	request, err := http.NewRequest("PUT", fmt.Sprintf("http://event-service/v1/events/%s?%s", idStr, r.URL.RawQuery), r.Body)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -404,6 +436,125 @@ func (app *application) applyToEventHandler(w http.ResponseWriter, r *http.Reque
 	app.handleResponseStatus(w, r, response.StatusCode, payload)
 }
 
+func (app *application) checkInAttendeeHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("POST", fmt.Sprintf("http://event-service/v1/events/%s/checkin", idStr), r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) waitlistPositionHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("GET", fmt.Sprintf("http://event-service/v1/events/%s/waitlist", idStr), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+// changeEventStatusHandler returns a handler that forwards one of the event
+// status transitions, such as submit or approve, to the event service, along
+// with the scope of the change for recurring events.
+func (app *application) changeEventStatusHandler(transition string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		if idStr == "" {
+			app.badRequestResponse(w, r, errors.New("missing id"))
+			return
+		}
+
+		request, err := http.NewRequest("POST", fmt.Sprintf("http://event-service/v1/events/%s/%s?%s", idStr, transition, r.URL.RawQuery), r.Body)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		request.Header = r.Header
+
+		client := &http.Client{}
+		response, err := client.Do(request)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		defer response.Body.Close()
+
+		responseBody, err := io.ReadAll(response.Body)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		var payload map[string]any
+		if err := json.Unmarshal(responseBody, &payload); err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.handleResponseStatus(w, r, response.StatusCode, payload)
+	}
+}
+
 func (app *application) removeUserEventApplication(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	if idStr == "" {
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 type envelope map[string]any
@@ -107,17 +110,52 @@ func (app *application) handleResponseStatus(w http.ResponseWriter, r *http.Requ
 			}
 		}
 		app.failedValidationResponse(w, r, validationErrors)
+	case http.StatusNotFound:
+		app.notFoundResponse(w, r)
 	case http.StatusConflict:
+		if message, ok := payload["error"].(string); ok {
+			app.errorResponse(w, r, http.StatusConflict, message)
+			return
+		}
 		app.editConflictResponse(w, r)
 	case http.StatusUnauthorized:
 		app.invalidCredentialsResponse(w, r)
 	case http.StatusForbidden:
+		// Inactive, disabled and unprivileged accounts are all turned away
+		// with a 403, so the service's own message says which it is.
+		if message, ok := payload["error"].(string); ok {
+			app.errorResponse(w, r, http.StatusForbidden, message)
+			return
+		}
 		app.inactiveAccountResponse(w, r)
+	case http.StatusTooManyRequests:
+		app.lockedOutResponse(w, r)
 	default:
 		app.serverErrorResponse(w, r, fmt.Errorf("unexpected status code %d from authentication service", statusCode))
 	}
 }
 
+// forwardClient passes on the caller's address and the broker's request ID.
+// The authentication service uses both to count failed logins and to fill in
+// its audit log, and would otherwise only ever see the broker. The address is
+// the one realIP settled on; forwarding headers copied from the caller are
+// dropped, so only the broker's own X-Real-IP reaches the service.
+func forwardClient(r *http.Request, request *http.Request) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	for _, header := range []string{"Forwarded", "X-Forwarded-For", "True-Client-IP"} {
+		request.Header.Del(header)
+	}
+	request.Header.Set("X-Real-IP", ip)
+
+	if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+		request.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+}
+
 func (app *application) background(fn func()) {
 	go func() {
 		defer func() {
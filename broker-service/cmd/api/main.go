@@ -18,26 +18,31 @@ const (
 type config struct {
 	port string
 	env  string
-	jwt  struct {
-		secret string
-	}
 }
 
 type application struct {
-	config config
-	logger *log.Logger
+	config  config
+	logger  *log.Logger
+	proxies *trustedProxies
 }
 
 func main() {
 	var cfg config
 	cfg.port = webPort
 	cfg.env = webEnv
-	cfg.jwt.secret = os.Getenv("JWT_SECRET")
+
+	// The broker faces clients directly unless a load balancer is put in
+	// front of it and listed here.
+	proxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	logger := log.New(os.Stdout, "", log.Ldate|log.LUTC)
 	app := &application{
-		config: cfg,
-		logger: logger,
+		config:  cfg,
+		logger:  logger,
+		proxies: proxies,
 	}
 
 	log.Printf("starting user service on %s\n", cfg.port)
@@ -51,6 +56,6 @@ func main() {
 	}
 
 	logger.Printf("starting %s server on %s", cfg.env, srv.Addr)
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	log.Fatal(err)
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyLookupTTL is how long the addresses of a proxy given by host name are
+// cached, so a restarted container with a new address is picked up.
+const proxyLookupTTL = time.Minute
+
+// trustedProxies lists the peers whose X-Real-IP and X-Forwarded-For headers
+// are believed. Anyone else could set them to dodge the authentication
+// service's login lockouts or to forge addresses in its audit log, so for them
+// the connection's own address is used. Proxies are given as IP addresses, CIDR ranges or host names.
+type trustedProxies struct {
+	networks []*net.IPNet
+	hosts    []string
+
+	mu       sync.Mutex
+	resolved []net.IP
+	expires  time.Time
+}
+
+func parseTrustedProxies(list string) (*trustedProxies, error) {
+	proxies := &trustedProxies{}
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			proxies.networks = append(proxies.networks, network)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			proxies.networks = append(proxies.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+		default:
+			proxies.hosts = append(proxies.hosts, entry)
+		}
+	}
+
+	return proxies, nil
+}
+
+func (p *trustedProxies) trusts(ip net.IP) bool {
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	if len(p.hosts) == 0 {
+		return false
+	}
+
+	for _, resolved := range p.resolveHosts() {
+		if resolved.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *trustedProxies) resolveHosts() []net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Now().Before(p.expires) {
+		return p.resolved
+	}
+
+	var resolved []net.IP
+	for _, host := range p.hosts {
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			continue
+		}
+		resolved = append(resolved, addrs...)
+	}
+
+	p.resolved = resolved
+	p.expires = time.Now().Add(proxyLookupTTL)
+
+	return resolved
+}
+
+// clientIP returns the address of the client behind the request. Forwarded
+// headers are only read when the peer is a trusted proxy, and X-Forwarded-For
+// is read from the right, skipping further trusted proxies, since everything
+// to the left of the last trusted hop was written by the client.
+func (p *trustedProxies) clientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(peer)
+	if ip == nil || !p.trusts(ip) {
+		return peer
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+
+		peer = hop.String()
+		if !p.trusts(hop) {
+			break
+		}
+	}
+
+	return peer
+}
+
+// realIP replaces the request's RemoteAddr with the client's address as
+// clientIP sees it. It stands in for chi's middleware.RealIP, which believes
+// the headers whoever sent them.
+func (app *application) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = app.proxies.clientIP(r)
+		next.ServeHTTP(w, r)
+	})
+}
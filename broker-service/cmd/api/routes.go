@@ -14,14 +14,14 @@ func (app *application) routes() http.Handler {
 
 	mux.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Expected-Version", "X-API-Key"},
+		ExposedHeaders:   []string{"Link", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 	mux.Use(middleware.RequestID)
-	mux.Use(middleware.RealIP)
+	mux.Use(app.realIP)
 	mux.Use(middleware.Logger)
 	mux.Use(middleware.Recoverer)
 	mux.Use(middleware.Timeout(60 * time.Second))
@@ -30,16 +30,60 @@ func (app *application) routes() http.Handler {
 
 	mux.Post("/", app.Broker)
 	mux.Post("/v1/login", app.loginHandler)
+	mux.Post("/v1/login/mfa", app.loginMFAHandler)
+	mux.Post("/v1/magic-link", app.magicLinkHandler)
+	mux.Post("/v1/login/magic-link", app.magicLinkLoginHandler)
+	mux.Get("/v1/login/oidc", app.oidcAuthorizeHandler)
+	mux.Post("/v1/login/oidc", app.oidcLoginHandler)
 	mux.Post("/v1/register", app.registerHandler)
 	mux.Post("/v1/verify", app.verifyTokenHandler)
+	mux.Post("/v1/refresh", app.refreshHandler)
+	mux.Delete("/v1/logout", app.logoutHandler)
+	mux.Post("/v1/forgot-password", app.forgotPasswordHandler)
+	mux.Put("/v1/reset-password", app.resetPasswordHandler)
+	mux.Put("/v1/unlock", app.unlockAccountHandler)
+	mux.Put("/v1/confirm-email", app.confirmEmailHandler)
+	mux.Get("/v1/users/me", app.showProfileHandler)
+	mux.Patch("/v1/users/me", app.updateProfileHandler)
+	mux.Post("/v1/users/me/totp", app.enrollTOTPHandler)
+	mux.Put("/v1/users/me/totp", app.confirmTOTPHandler)
+	mux.Delete("/v1/users/me/totp", app.disableTOTPHandler)
+
+	mux.Get("/v1/api-keys", app.listAPIKeysHandler)
+	mux.Post("/v1/api-keys", app.createAPIKeyHandler)
+	mux.Delete("/v1/api-keys/{id}", app.deleteAPIKeyHandler)
+
+	mux.Get("/v1/admin/audit-log", app.listAuditLogHandler)
+	mux.Get("/v1/admin/audit-log/export", app.exportAuditLogHandler)
+	mux.Post("/v1/admin/tokens/cleanup", app.cleanupTokensHandler)
+	mux.Get("/v1/admin/invitations", app.listInvitationsHandler)
+	mux.Post("/v1/admin/invitations", app.createInvitationHandler)
+	mux.Delete("/v1/admin/invitations/{id}", app.deleteInvitationHandler)
+	mux.Get("/v1/admin/users", app.listUsersHandler)
+	mux.Post("/v1/admin/users/import", app.importUsersHandler)
+	mux.Get("/v1/admin/users/{id}", app.showUserHandler)
+	mux.Patch("/v1/admin/users/{id}", app.updateUserStatusHandler)
+	mux.Post("/v1/admin/users/{id}/activation", app.resendActivationHandler)
+	mux.Delete("/v1/admin/users/{id}/lockout", app.clearLockoutHandler)
+	mux.Put("/v1/admin/users/{id}/roles/{role}", app.userRoleHandler)
+	mux.Delete("/v1/admin/users/{id}/roles/{role}", app.userRoleHandler)
 
 	mux.Get("/v1/events", app.getAllEventsHandler)
+	mux.Get("/v1/events/search", app.searchEventsHandler)
 	mux.Get("/v1/events/{id}", app.getEventByIDHandler)
 	mux.Post("/v1/events", app.createEventHandler)
 	mux.Put("/v1/events/{id}", app.updateEventHandler)
 	mux.Delete("/v1/events/{id}", app.deleteEventHandler)
 	mux.Delete("/v1/events/{id}/unapply", app.removeUserEventApplication)
 	mux.Post("/v1/events/{id}/apply", app.applyToEventHandler)
+	mux.Post("/v1/events/{id}/checkin", app.checkInAttendeeHandler)
+	mux.Get("/v1/events/{id}/waitlist", app.waitlistPositionHandler)
+	mux.Post("/v1/events/{id}/submit", app.changeEventStatusHandler("submit"))
+	mux.Post("/v1/events/{id}/approve", app.changeEventStatusHandler("approve"))
+	mux.Post("/v1/events/{id}/reject", app.changeEventStatusHandler("reject"))
+	mux.Post("/v1/events/{id}/publish", app.changeEventStatusHandler("publish"))
+	mux.Post("/v1/events/{id}/cancel", app.changeEventStatusHandler("cancel"))
+	mux.Post("/v1/events/{id}/complete", app.changeEventStatusHandler("complete"))
 	
 	mux.Get("/v1/events/user", app.viewUnsubedEventsHandler)
 
@@ -2,8 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
@@ -13,7 +17,82 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	request.Header = r.Header
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) magicLinkLoginHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/tokens/magic-link/authentication", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) oidcAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/oidc/authorize", nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
 
 	client := &http.Client{}
 	response, err := client.Do(request)
@@ -38,6 +117,76 @@ func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
 	app.handleResponseStatus(w, r, response.StatusCode, payload)
 }
 
+func (app *application) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/tokens/oidc", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/tokens/mfa", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
 func (app *application) registerHandler(w http.ResponseWriter, r *http.Request) {
 	request, err := http.NewRequest("POST", "http://authentication-service/v1/users", r.Body)
 	if err != nil {
@@ -45,7 +194,8 @@ func (app *application) registerHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	request.Header = r.Header
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
 
 	client := &http.Client{}
 	response, err := client.Do(request)
@@ -77,6 +227,501 @@ func (app *application) verifyTokenHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/tokens/refresh", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("DELETE", "http://authentication-service/v1/tokens", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/tokens/password-reset", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) magicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/tokens/magic-link", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("PUT", "http://authentication-service/v1/users/password", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) unlockAccountHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("PUT", "http://authentication-service/v1/users/unlocked", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) showProfileHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/users/me", nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("PATCH", "http://authentication-service/v1/users/me", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) confirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("PUT", "http://authentication-service/v1/users/email", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/users/me/totp", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("PUT", "http://authentication-service/v1/users/me/totp", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("DELETE", "http://authentication-service/v1/users/me/totp", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("GET", "http://authentication-service/v1/api-keys", nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := http.NewRequest("POST", "http://authentication-service/v1/api-keys", r.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	request.Header = r.Header.Clone()
+	forwardClient(r, request)
+
+	client := &http.Client{}
+	response, err := client.Do(request)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseBody, &payload); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.handleResponseStatus(w, r, response.StatusCode, payload)
+}
+
+func (app *application) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		app.badRequestResponse(w, r, errors.New("missing id"))
+		return
+	}
+
+	request, err := http.NewRequest("DELETE", fmt.Sprintf("http://authentication-service/v1/api-keys/%s", idStr), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	request.Header = r.Header
 
 	client := &http.Client{}
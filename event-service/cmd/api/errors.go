@@ -96,3 +96,9 @@ func (app *application) onlyUsersResponse(w http.ResponseWriter, r *http.Request
 	message := "only authenticated users can access this resource"
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+//lint:ignore U1000 notPermittedResponse is used by error handling methods
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
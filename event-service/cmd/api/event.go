@@ -3,25 +3,100 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// getAllEventsHandler lists events a page at a time. Events can be filtered
+// by type, status, city, country, organizer email, series and a from/to date
+// range, and sorted by date, name, created_at or max_capacity in either
+// direction. Every occurrence of a recurring event is an event of its own, so a
+// date range lists each occurrence that falls in it. Only the events the
+// requester may see are listed; see eventVisibility.
 func (app *application) getAllEventsHandler(w http.ResponseWriter, r *http.Request) {
 	app.Logger.Println("GetAllEvents called")
 
-	events, err := app.models.Event.GetAllEvents()
+	errors := map[string]string{}
+
+	filters := app.readEventFilters(r.URL.Query(), errors)
+	if len(errors) > 0 {
+		app.failedValidationResponse(w, r, errors)
+		return
+	}
+
+	app.eventVisibility(r, &filters)
+
+	events, metadata, err := app.models.Event.ListEvents(filters)
 	if err != nil {
 		app.Logger.Printf("Error fetching events: %v", err)
 		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to fetch events"}, nil)
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"events": events}, nil)
+	app.writeJSON(w, http.StatusOK, envelope{"events": events, "metadata": metadata}, nil)
+}
+
+// searchEventsHandler finds events whose name, description or organizer names
+// match q, best match first. It takes the same filters and paging parameters
+// as getAllEventsHandler, and is forgiving enough of unfinished words and typos
+// to back an autocomplete box.
+func (app *application) searchEventsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	errors := map[string]string{}
+
+	query := qs.Get("q")
+	if message := data.ValidateSearchQuery(query); message != "" {
+		errors["q"] = message
+	}
+
+	filters := app.readEventFilters(qs, errors)
+	if len(errors) > 0 {
+		app.failedValidationResponse(w, r, errors)
+		return
+	}
+
+	app.eventVisibility(r, &filters)
+
+	results, metadata, err := app.models.Event.SearchEvents(query, filters)
+	if err != nil {
+		app.Logger.Printf("Error searching events: %v", err)
+		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to search events"}, nil)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+}
+
+// readEventFilters reads the filter, sort and paging parameters shared by the
+// event listing and search, recording any problems in errors.
+func (app *application) readEventFilters(qs url.Values, errors map[string]string) data.EventFilters {
+	filters := data.EventFilters{
+		Type:           data.EventType(strings.ToUpper(app.readString(qs, "type", ""))),
+		Status:         strings.ToUpper(app.readString(qs, "status", "")),
+		City:           app.readString(qs, "city", ""),
+		Country:        app.readString(qs, "country", ""),
+		OrganizerEmail: app.readString(qs, "organizer_email", ""),
+		SeriesID:       app.readObjectID(qs, "series_id", errors),
+		From:           app.readTime(qs, "from", errors),
+		To:             app.readTime(qs, "to", errors),
+		Sort:           app.readString(qs, "sort", "date"),
+		Direction:      strings.ToLower(app.readString(qs, "direction", "asc")),
+		Page:           app.readInt(qs, "page", 1, errors),
+		PageSize:       app.readInt(qs, "page_size", 20, errors),
+	}
+
+	for key, message := range data.ValidateEventFilters(filters) {
+		if _, ok := errors[key]; !ok {
+			errors[key] = message
+		}
+	}
+
+	return filters
 }
 
 func (app *application) getEventByIDHandler(w http.ResponseWriter, r *http.Request) {
@@ -40,7 +115,7 @@ func (app *application) getEventByIDHandler(w http.ResponseWriter, r *http.Reque
 		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to fetch event"}, nil)
 		return
 	}
-	if event == nil {
+	if event == nil || !app.canViewEvent(r, event) {
 		app.Logger.Println("Event not found")
 		app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
 		return
@@ -59,6 +134,11 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if event.Recurrence != nil {
+		app.createEventSeries(w, r, &event)
+		return
+	}
+
 	createdEvent, err := app.models.Event.CreateEvent(&event)
 	if err != nil {
 		app.Logger.Printf("Error creating event: %v", err)
@@ -70,6 +150,7 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 		ID:       primitive.NewObjectID(),
 		EventID:  createdEvent.ID,
 		Attendee: []string{},
+		Waitlist: []string{},
 	})
 	if err != nil {
 		app.Logger.Printf("Error creating event app: %v", err)
@@ -77,30 +158,8 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	location := fmt.Sprintf("%s,\n%s,\n%s,\n%s", createdEvent.Location.Address, createdEvent.Location.City, createdEvent.Location.State, createdEvent.Location.Country)
-
-	payload := map[string]any{
-		"event_type":        createdEvent.Type,
-		"event_name":        createdEvent.Name,
-		"event_date":        createdEvent.Date,
-		"event_description": createdEvent.Description,
-		"event_location":    location,
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		app.Logger.Printf("Error marshaling payload: %v", err)
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
-	err = app.pushToQueue("event_add", string(jsonPayload))
-	if err != nil {
-		app.Logger.Printf("Error pushing event to queue: %v", err)
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
+	// New events start as drafts and are only announced to subscribers once
+	// they are published.
 	app.writeJSON(w, http.StatusCreated, envelope{"event": createdEvent}, nil)
 }
 
@@ -122,7 +181,27 @@ func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	_, err = app.models.Event.UpdateEvent(id, &event)
+	email, roles, err := app.tokenExtractor.extractIdentity(r)
+	if err != nil {
+		app.writeJSON(w, http.StatusUnauthorized, envelope{"error": "Invalid token"}, nil)
+		return
+	}
+
+	// An organizer's edit sends an approved or published event back for
+	// review. Admins are the reviewers, so their edits leave the status be.
+	editor := email
+	if app.Contains(roles, data.RoleAdmin) {
+		editor = ""
+	}
+
+	// Occurrences of a recurring event can also be edited together; see
+	// updateEventSeries.
+	if scope := app.readString(r.URL.Query(), "scope", data.ScopeThis); scope != data.ScopeThis {
+		app.updateEventSeries(w, r, id, &event, scope, editor)
+		return
+	}
+
+	_, err = app.models.Event.UpdateEvent(id, &event, editor)
 	if err != nil {
 		app.Logger.Printf("Error updating event: %v", err)
 		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to update event"}, nil)
@@ -182,7 +261,13 @@ func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	if event != nil {
-		err = app.models.Event.DeleteEvent(id)
+		// Deleting an occurrence of a recurring event excludes its date from
+		// the series.
+		if event.Recurrence != nil {
+			err = app.models.Event.ExcludeOccurrence(event)
+		} else {
+			err = app.models.Event.DeleteEvent(id)
+		}
 		if err != nil {
 			app.Logger.Printf("Error deleting event: %v", err)
 			app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to delete event"}, nil)
@@ -219,6 +304,15 @@ func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Reques
 				return
 			}
 		}
+
+		// The applications go with the event, so that neither a deleted event
+		// nor an excluded occurrence leaves attendees behind.
+		err = app.models.EventApps.DeleteEventApp(context.Background(), id)
+		if err != nil {
+			app.Logger.Printf("Error deleting event apps: %v", err)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 	}
 
 	app.writeJSON(w, http.StatusOK, envelope{"message": "Event deleted successfully"}, nil)
@@ -252,6 +346,9 @@ func (app *application) viewUnsubscribedEventsHandler(w http.ResponseWriter, r *
 	// Filter out subscribed events to get unsubscribed events
 	var unsubscribedEvents []data.Event
 	for _, event := range allEvents {
+		if event.Status != data.StatusPublished {
+			continue
+		}
 		if _, exists := subscribedEventIDs[event.ID]; !exists {
 			unsubscribedEvents = append(unsubscribedEvents, event)
 		}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -38,6 +37,10 @@ func (app *application) createEventAppHandler(w http.ResponseWriter, r *http.Req
 
 	err = app.models.EventApps.CreateEventApp(context.Background(), &eventAppData)
 	if err != nil {
+		if errors.Is(err, data.ErrDuplicateEventApp) {
+			app.errorResponse(w, r, http.StatusConflict, "the event already has applications")
+			return
+		}
 		app.Logger.Printf("Error creating event app: %v\n", err)
 		app.serverErrorResponse(w, r, err)
 		return
@@ -121,6 +124,9 @@ func (app *application) deleteEventAppHandler(w http.ResponseWriter, r *http.Req
 	app.writeJSON(w, http.StatusNoContent, envelope{"message": "Event app deleted successfully"}, nil)
 }
 
+// applyToEventHandler gives the user a place on a published event, or a place
+// on its waitlist when it is full. Whether they get in is decided by a single
+// conditional update in the model, not by what this handler reads beforehand.
 func (app *application) applyToEventHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	objID, err := primitive.ObjectIDFromHex(idStr)
@@ -134,68 +140,132 @@ func (app *application) applyToEventHandler(w http.ResponseWriter, r *http.Reque
 		app.writeJSON(w, http.StatusUnauthorized, envelope{"error": "Invalid token"}, nil)
 		return
 	}
-	eventApp, err := app.models.EventApps.GetEventApp(context.Background(), objID)
+
+	event, err := app.models.Event.GetEventByID(objID)
 	if err != nil {
 		if errors.Is(err, data.ErrNoRecords) {
-			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event app not found"}, nil)
+			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
 			return
 		}
-		app.Logger.Printf("Error fetching event app with ID %s: %v\n", idStr, err)
+		app.Logger.Printf("Error fetching event with ID %s: %v\n", objID.Hex(), err)
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-
-	if app.Contains(eventApp.Attendee, email) {
-		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "You already applied to this event"}, nil)
+	if event.Status != data.StatusPublished {
+		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Event is not open for applications"}, nil)
 		return
 	}
 
-	event, err := app.models.Event.GetEventByID(objID)
+	application, err := app.models.EventApps.Apply(email, event)
+	if errors.Is(err, data.ErrCountNotUpdated) {
+		// The user has their place; the counter is recounted at the next start.
+		app.Logger.Printf("Error counting application to event %s: %v\n", idStr, err)
+		err = nil
+	}
 	if err != nil {
-		if errors.Is(err, data.ErrNoRecords) {
-			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
-			return
+		switch {
+		case errors.Is(err, data.ErrNoRecords):
+			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event app not found"}, nil)
+		case errors.Is(err, data.ErrAlreadyApplied):
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "You already applied to this event"}, nil)
+		case errors.Is(err, data.ErrWaitlisted):
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "You are already on the waitlist for this event", "waitlist_position": application.WaitlistPosition}, nil)
+		case errors.Is(err, data.ErrEventEnded):
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Event has ended"}, nil)
+		default:
+			app.Logger.Printf("Error applying to event %s: %v\n", idStr, err)
+			app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to apply to event"}, nil)
 		}
-		app.Logger.Printf("Error fetching event with ID %s: %v\n", objID.Hex(), err)
-		app.serverErrorResponse(w, r, err)
 		return
 	}
-	if event.Date.Before(time.Now()) {
-		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Event has ended"}, nil)
+
+	if !application.Attending {
+		app.writeJSON(w, http.StatusAccepted, envelope{"message": "Event is full, you have been added to the waitlist", "waitlist_position": application.WaitlistPosition}, nil)
 		return
 	}
 
-	err = app.models.EventApps.AddAttendeeToEvent(email, objID)
+	app.background(func() {
+		app.queueEventMessage("event_register", map[string]any{
+			"event_name":     event.Name,
+			"event_date":     event.Date,
+			"event_location": fmt.Sprintf("%s,%s,%s,%s", event.Location.Address, event.Location.City, event.Location.State, event.Location.Country),
+			"emails":         []string{email},
+		})
+	})
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "Applied to event successfully"}, nil)
+}
+
+// removeUserEventApplication takes the user off an event's attendee list or
+// waitlist. A place freed on the attendee list goes to the first user on the
+// waitlist, who is told about it.
+func (app *application) removeUserEventApplication(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	objID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
-		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to apply to event"}, nil)
+		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Invalid ID"}, nil)
 		return
 	}
 
-	payload := map[string]any{
-		"event_name":     event.Name,
-		"event_date":     event.Date,
-		"event_location": fmt.Sprintf("%s,%s,%s,%s", event.Location.Address, event.Location.City, event.Location.State, event.Location.Country),
-		"emails":         []string{email},
+	email, _, _, err := app.tokenExtractor.extractTokenData(r)
+	if err != nil {
+		app.writeJSON(w, http.StatusUnauthorized, envelope{"error": "Invalid token"}, nil)
+		return
 	}
 
-	jsonPayload, err := json.Marshal(payload)
+	event, err := app.models.Event.GetEventByID(objID)
 	if err != nil {
-		app.Logger.Printf("Error marshaling payload: %v\n", err)
+		if errors.Is(err, data.ErrNoRecords) {
+			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
+			return
+		}
+		app.Logger.Printf("Error fetching event with ID %s: %v\n", objID.Hex(), err)
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.pushToQueue("event_register", string(jsonPayload))
+	cancellation, err := app.models.EventApps.Unapply(email, event)
+	if errors.Is(err, data.ErrCountNotUpdated) {
+		app.Logger.Printf("Error counting cancellation for event %s: %v\n", idStr, err)
+		err = nil
+	}
 	if err != nil {
-		app.Logger.Printf("Error pushing to queue: %v\n", err)
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrNoRecords):
+			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event app not found"}, nil)
+		case errors.Is(err, data.ErrNotApplied):
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "You have not applied to this event"}, nil)
+		case errors.Is(err, data.ErrEventEnded):
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Event has ended"}, nil)
+		default:
+			app.Logger.Printf("Error removing application to event %s: %v\n", idStr, err)
+			app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to remove user event application"}, nil)
+		}
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"message": "Applied to event successfully"}, nil)
+	if promoted := cancellation.Promoted; promoted != "" {
+		app.background(func() {
+			app.queueEventMessage("event_waitlist_promoted", map[string]any{
+				"emails":         []string{promoted},
+				"event_name":     event.Name,
+				"event_date":     event.Date,
+				"event_location": fmt.Sprintf("%s,%s,%s,%s", event.Location.Address, event.Location.City, event.Location.State, event.Location.Country),
+			})
+		})
+	}
+
+	if !cancellation.WasAttending {
+		app.writeJSON(w, http.StatusOK, envelope{"message": "Removed from the waitlist successfully"}, nil)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "Removed user event application successfully"}, nil)
 }
 
-func (app *application) removeUserEventApplication(w http.ResponseWriter, r *http.Request) {
+// waitlistPositionHandler tells the user where they are on an event's
+// waitlist.
+func (app *application) waitlistPositionHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	objID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -220,32 +290,49 @@ func (app *application) removeUserEventApplication(w http.ResponseWriter, r *htt
 		return
 	}
 
-	if !app.Contains(eventApp.Attendee, email) {
-		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "You have not applied to this event"}, nil)
+	position := eventApp.WaitlistPosition(email)
+	if position == 0 {
+		app.writeJSON(w, http.StatusNotFound, envelope{"error": "You are not on the waitlist for this event"}, nil)
 		return
 	}
 
-	event, err := app.models.Event.GetEventByID(objID)
+	app.writeJSON(w, http.StatusOK, envelope{"waitlist_position": position, "waitlist_length": len(eventApp.Waitlist)}, nil)
+}
+
+func (app *application) checkInAttendeeHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	objID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
-		if errors.Is(err, data.ErrNoRecords) {
-			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
-			return
-		}
-		app.Logger.Printf("Error fetching event with ID %s: %v\n", objID.Hex(), err)
-		app.serverErrorResponse(w, r, err)
+		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Invalid ID"}, nil)
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
-	if event.Date.Before(time.Now()) {
-		app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Event has ended"}, nil)
+
+	if input.Email == "" {
+		app.failedValidationResponse(w, r, map[string]string{"email": "must be provided"})
 		return
 	}
 
-	err = app.models.EventApps.RemoveAttendeeFromEvent(email, objID)
+	err = app.models.EventApps.CheckInAttendee(input.Email, objID)
 	if err != nil {
-		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to remove user event application"}, nil)
+		if errors.Is(err, data.ErrNotApplied) {
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "User has not applied to this event"}, nil)
+			return
+		}
+		app.Logger.Printf("Error checking in attendee for event %s: %v\n", idStr, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
-	app.writeJSON(w, http.StatusOK, envelope{"message": "Removed user event application successfully"}, nil)
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "Attendee checked in successfully"}, nil)
 }
 
 func (app *application) viewAppliedEventsHandler(w http.ResponseWriter, r *http.Request) {
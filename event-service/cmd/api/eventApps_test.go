@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -48,13 +53,18 @@ func (m *MockEventAppModel) ListEventApps(ctx context.Context, filter bson.M, op
 	return args.Get(0).([]*data.EventApps), args.Error(1)
 }
 
-func (m *MockEventAppModel) AddAttendeeToEvent(name string, eventId primitive.ObjectID) error {
-	args := m.Called(name, eventId)
-	return args.Error(0)
+func (m *MockEventAppModel) Apply(email string, event *data.Event) (*data.Application, error) {
+	args := m.Called(email, event)
+	return args.Get(0).(*data.Application), args.Error(1)
+}
+
+func (m *MockEventAppModel) Unapply(email string, event *data.Event) (*data.Cancellation, error) {
+	args := m.Called(email, event)
+	return args.Get(0).(*data.Cancellation), args.Error(1)
 }
 
-func (m *MockEventAppModel) RemoveAttendeeFromEvent(name string, eventId primitive.ObjectID) error {
-	args := m.Called(name, eventId)
+func (m *MockEventAppModel) CheckInAttendee(email string, eventId primitive.ObjectID) error {
+	args := m.Called(email, eventId)
 	return args.Error(0)
 }
 
@@ -72,6 +82,79 @@ func (m *MockTokenExtractor) extractTokenData(r *http.Request) (string, bool, bo
 	return args.String(0), args.Bool(1), args.Bool(2), args.Error(3)
 }
 
+func (m *MockTokenExtractor) extractRoles(r *http.Request) ([]string, error) {
+	args := m.Called(r)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// extractIdentity is answered from the extractTokenData and extractRoles
+// expectations, so tests set those up whichever method the handler calls.
+func (m *MockTokenExtractor) extractIdentity(r *http.Request) (string, []string, error) {
+	email, _, _, err := m.extractTokenData(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	roles, err := m.extractRoles(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return email, roles, nil
+}
+
+func TestCreateEventAppHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		setupMock      func(mockEventAppModel *MockEventAppModel)
+	}{
+		{
+			name:           "Missing Event ID",
+			body:           `{"attendee": []}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"EventID is required"}`,
+			setupMock:      func(mockEventAppModel *MockEventAppModel) {},
+		},
+		{
+			name:           "Event Already Has Applications",
+			body:           fmt.Sprintf(`{"eventid": %q, "attendee": []}`, primitive.NewObjectID().Hex()),
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"the event already has applications"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel) {
+				mockEventAppModel.On("CreateEventApp", mock.Anything, mock.AnythingOfType("*data.EventApps")).Return(data.ErrDuplicateEventApp)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventAppModel := new(MockEventAppModel)
+
+			app := &application{
+				Logger: log.New(io.Discard, "", 0),
+				config: config{port: "80", env: "development"},
+				models: data.Models{EventApps: mockEventAppModel},
+			}
+
+			tt.setupMock(mockEventAppModel)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/eventApps", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(app.createEventAppHandler).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockEventAppModel.AssertExpectations(t)
+		})
+	}
+}
+
 func TestApplyToEventHandler(t *testing.T) {
 	mockEventAppModel := new(MockEventAppModel)
 	mockEventModel := new(MockEventModel)
@@ -93,6 +176,9 @@ func TestApplyToEventHandler(t *testing.T) {
 		tokenExtractor: mockTokenExtractor,
 	}
 
+	publishedEvent := &data.Event{Name: "Test Event", Status: data.StatusPublished, MaxCapacity: 2, Date: time.Now().Add(1 * time.Hour), Location: data.Location{Address: "123 Test St", City: "Test City", State: "Test State", Country: "Test Country"}}
+	endedEvent := &data.Event{Name: "Test Event", Status: data.StatusPublished, Date: time.Now().Add(-1 * time.Hour), Location: data.Location{Address: "123 Test St", City: "Test City", State: "Test State", Country: "Test Country"}}
+
 	tests := []struct {
 		name           string
 		eventApp       interface{}
@@ -111,7 +197,8 @@ func TestApplyToEventHandler(t *testing.T) {
 			expectedBody:   `{"error":"Event app not found"}`,
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
 				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{}, data.ErrNoRecords)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(publishedEvent, nil)
+				mockEventAppModel.On("Apply", "test@example.com", publishedEvent).Return((*data.Application)(nil), data.ErrNoRecords)
 			},
 		},
 		{
@@ -138,7 +225,8 @@ func TestApplyToEventHandler(t *testing.T) {
 			expectedBody:   `{"error":"You already applied to this event"}`,
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
 				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{Attendee: []string{"test@example.com"}}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(publishedEvent, nil)
+				mockEventAppModel.On("Apply", "test@example.com", publishedEvent).Return(&data.Application{Attending: true}, data.ErrAlreadyApplied)
 			},
 		},
 		{
@@ -152,10 +240,52 @@ func TestApplyToEventHandler(t *testing.T) {
 			expectedBody:   `{"message":"Applied to event successfully"}`,
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
 				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{Attendee: []string{}}, nil)
-				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Name: "Test Event", Date: time.Now().Add(1 * time.Hour), Location: data.Location{Address: "123 Test St", City: "Test City", State: "Test State", Country: "Test Country"}}, nil)
-				mockEventAppModel.On("AddAttendeeToEvent", "test@example.com", mock.AnythingOfType("primitive.ObjectID")).Return(nil)
-				mockEventAppModel.On("AddAttendeeToEvent", mock.Anything, mock.Anything).Return(nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(publishedEvent, nil)
+				mockEventAppModel.On("Apply", "test@example.com", publishedEvent).Return(&data.Application{Attending: true}, nil)
+			},
+		},
+		{
+			name: "Full Event Adds To Waitlist",
+			eventApp: struct {
+				EventID string
+			}{
+				EventID: primitive.NewObjectID().Hex(),
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"message":"Event is full, you have been added to the waitlist","waitlist_position":2}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(publishedEvent, nil)
+				mockEventAppModel.On("Apply", "test@example.com", publishedEvent).Return(&data.Application{WaitlistPosition: 2}, nil)
+			},
+		},
+		{
+			name: "Already On Waitlist",
+			eventApp: struct {
+				EventID string
+			}{
+				EventID: primitive.NewObjectID().Hex(),
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"You are already on the waitlist for this event","waitlist_position":1}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(publishedEvent, nil)
+				mockEventAppModel.On("Apply", "test@example.com", publishedEvent).Return(&data.Application{WaitlistPosition: 1}, data.ErrWaitlisted)
+			},
+		},
+		{
+			name: "Event Not Published",
+			eventApp: struct {
+				EventID string
+			}{
+				EventID: primitive.NewObjectID().Hex(),
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Event is not open for applications"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Name: "Test Event", Status: data.StatusDraft, Date: time.Now().Add(1 * time.Hour)}, nil)
 			},
 		},
 		{
@@ -169,8 +299,8 @@ func TestApplyToEventHandler(t *testing.T) {
 			expectedBody:   `{"error":"Event has ended"}`,
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
 				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{Attendee: []string{}}, nil)
-				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Name: "Test Event", Date: time.Now().Add(-1 * time.Hour), Location: data.Location{Address: "123 Test St", City: "Test City", State: "Test State", Country: "Test Country"}}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(endedEvent, nil)
+				mockEventAppModel.On("Apply", "test@example.com", endedEvent).Return((*data.Application)(nil), data.ErrEventEnded)
 			},
 		},
 	}
@@ -208,3 +338,368 @@ func TestApplyToEventHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveUserEventApplication(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	fullEvent := &data.Event{Name: "Test Event", Status: data.StatusPublished, MaxCapacity: 2, Date: time.Now().Add(1 * time.Hour)}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedBody   string
+		setupMock      func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel)
+	}{
+		{
+			name:           "Leave Waitlist",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Removed from the waitlist successfully"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(fullEvent, nil)
+				mockEventAppModel.On("Unapply", "test@example.com", fullEvent).Return(&data.Cancellation{}, nil)
+			},
+		},
+		{
+			name:           "Freed Place Goes To Waitlist",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Removed user event application successfully"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(fullEvent, nil)
+				mockEventAppModel.On("Unapply", "test@example.com", fullEvent).Return(&data.Cancellation{WasAttending: true, Promoted: "c@example.com"}, nil)
+			},
+		},
+		{
+			name:           "Empty Waitlist",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Removed user event application successfully"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(fullEvent, nil)
+				mockEventAppModel.On("Unapply", "test@example.com", fullEvent).Return(&data.Cancellation{WasAttending: true}, nil)
+			},
+		},
+		{
+			name:           "Counter Not Updated",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Removed user event application successfully"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(fullEvent, nil)
+				mockEventAppModel.On("Unapply", "test@example.com", fullEvent).Return(&data.Cancellation{WasAttending: true}, fmt.Errorf("%w: connection reset", data.ErrCountNotUpdated))
+			},
+		},
+		{
+			name:           "Not Applied",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"You have not applied to this event"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(fullEvent, nil)
+				mockEventAppModel.On("Unapply", "test@example.com", fullEvent).Return((*data.Cancellation)(nil), data.ErrNotApplied)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventAppModel := new(MockEventAppModel)
+			mockEventModel := new(MockEventModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			app.models = data.Models{
+				EventApps: mockEventAppModel,
+				Event:     mockEventModel,
+			}
+			app.tokenExtractor = mockTokenExtractor
+
+			mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", false, true, nil)
+			tt.setupMock(mockEventAppModel, mockEventModel)
+
+			req := httptest.NewRequest(http.MethodDelete, "/v1/events/{id}/unapply", nil)
+			req.SetPathValue("id", primitive.NewObjectID().Hex())
+
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.removeUserEventApplication)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockEventAppModel.AssertExpectations(t)
+			mockEventModel.AssertExpectations(t)
+			mockTokenExtractor.AssertExpectations(t)
+		})
+	}
+}
+
+// lockedEventApps stands in for the event applications collection in
+// TestApplyToEventConcurrently. Like the conditional update in
+// data.EventAppModel.Apply, each application is checked and recorded in one
+// step, here under a mutex.
+type lockedEventApps struct {
+	*MockEventAppModel
+	mu           sync.Mutex
+	eventApps    data.EventApps
+	applications int
+}
+
+func (l *lockedEventApps) Apply(email string, event *data.Event) (*data.Application, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if position := l.eventApps.WaitlistPosition(email); position > 0 {
+		return &data.Application{WaitlistPosition: position}, data.ErrWaitlisted
+	}
+	if slices.Contains(l.eventApps.Attendee, email) {
+		return &data.Application{Attending: true}, data.ErrAlreadyApplied
+	}
+
+	if len(l.eventApps.Attendee) >= event.MaxCapacity {
+		l.eventApps.Waitlist = append(l.eventApps.Waitlist, email)
+		return &data.Application{WaitlistPosition: len(l.eventApps.Waitlist)}, nil
+	}
+
+	l.eventApps.Attendee = append(l.eventApps.Attendee, email)
+	l.applications++
+	return &data.Application{Attending: true}, nil
+}
+
+// emailTokenExtractor authenticates each request as the user named in its
+// X-Email header.
+type emailTokenExtractor struct{}
+
+func (emailTokenExtractor) extractTokenData(r *http.Request) (string, bool, bool, error) {
+	return r.Header.Get("X-Email"), false, true, nil
+}
+
+func (emailTokenExtractor) extractRoles(r *http.Request) ([]string, error) {
+	return []string{data.RoleAttendee}, nil
+}
+
+func (e emailTokenExtractor) extractIdentity(r *http.Request) (string, []string, error) {
+	roles, _ := e.extractRoles(r)
+	return r.Header.Get("X-Email"), roles, nil
+}
+
+func TestApplyToEventConcurrently(t *testing.T) {
+	const capacity = 5
+
+	event := &data.Event{Name: "Test Event", Status: data.StatusPublished, MaxCapacity: capacity, Date: time.Now().Add(1 * time.Hour)}
+
+	// apply sends one application per email at the same time and returns the
+	// responses in the same order.
+	apply := func(eventApps *lockedEventApps, emails []string) []*httptest.ResponseRecorder {
+		mockEventModel := new(MockEventModel)
+		mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(event, nil)
+
+		app := &application{
+			Logger:         log.New(io.Discard, "", 0),
+			config:         config{port: "80", env: "development"},
+			models:         data.Models{EventApps: eventApps, Event: mockEventModel},
+			tokenExtractor: emailTokenExtractor{},
+		}
+
+		id := primitive.NewObjectID().Hex()
+		responses := make([]*httptest.ResponseRecorder, len(emails))
+
+		var start, done sync.WaitGroup
+		start.Add(1)
+		for i, email := range emails {
+			done.Add(1)
+			go func() {
+				defer done.Done()
+
+				req := httptest.NewRequest(http.MethodPost, "/v1/events/{id}/apply", nil)
+				req.SetPathValue("id", id)
+				req.Header.Set("X-Email", email)
+
+				start.Wait()
+				responses[i] = httptest.NewRecorder()
+				app.applyToEventHandler(responses[i], req)
+			}()
+		}
+		start.Done()
+		done.Wait()
+
+		return responses
+	}
+
+	t.Run("Many Users", func(t *testing.T) {
+		eventApps := &lockedEventApps{MockEventAppModel: new(MockEventAppModel)}
+
+		emails := make([]string, 50)
+		for i := range emails {
+			emails[i] = fmt.Sprintf("user%d@example.com", i)
+		}
+
+		var attending int
+		positions := map[int]bool{}
+
+		for _, rr := range apply(eventApps, emails) {
+			var res struct {
+				WaitlistPosition int `json:"waitlist_position"`
+			}
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+			switch rr.Code {
+			case http.StatusOK:
+				attending++
+			case http.StatusAccepted:
+				assert.False(t, positions[res.WaitlistPosition], "waitlist position %d given out twice", res.WaitlistPosition)
+				positions[res.WaitlistPosition] = true
+			default:
+				t.Errorf("unexpected status %d: %s", rr.Code, rr.Body.String())
+			}
+		}
+
+		assert.Equal(t, capacity, attending)
+		assert.Len(t, positions, len(emails)-capacity)
+		assert.Len(t, eventApps.eventApps.Attendee, capacity)
+		assert.Len(t, eventApps.eventApps.Waitlist, len(emails)-capacity)
+		assert.Equal(t, capacity, eventApps.applications)
+	})
+
+	t.Run("Same User", func(t *testing.T) {
+		eventApps := &lockedEventApps{MockEventAppModel: new(MockEventAppModel)}
+
+		emails := make([]string, 20)
+		for i := range emails {
+			emails[i] = "test@example.com"
+		}
+
+		statuses := map[int]int{}
+		for _, rr := range apply(eventApps, emails) {
+			statuses[rr.Code]++
+			if rr.Code == http.StatusBadRequest {
+				assert.JSONEq(t, `{"error":"You already applied to this event"}`, rr.Body.String())
+			}
+		}
+
+		assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusBadRequest: len(emails) - 1}, statuses)
+		assert.Equal(t, []string{"test@example.com"}, eventApps.eventApps.Attendee)
+		assert.Equal(t, 1, eventApps.applications)
+	})
+}
+
+// TestApplyToEventWithMongo runs TestApplyToEventConcurrently's race against a
+// real database, where it is data.EventAppModel.Apply that has to hold up.
+func TestApplyToEventWithMongo(t *testing.T) {
+	const (
+		capacity = 5
+		users    = 30
+	)
+
+	db := testDatabase(t)
+	models := data.NewModels(db)
+
+	event, err := models.Event.CreateEvent(&data.Event{Name: "Test Event", MaxCapacity: capacity, Date: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Collection("events").UpdateByID(context.Background(), event.ID, bson.M{"$set": bson.M{"status": data.StatusPublished}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.EventApps.CreateEventApp(context.Background(), &data.EventApps{EventID: event.ID, Attendee: []string{}, Waitlist: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		Logger:         log.New(io.Discard, "", 0),
+		config:         config{port: "80", env: "development"},
+		models:         models,
+		tokenExtractor: emailTokenExtractor{},
+	}
+
+	// Every user sends two applications at the same time.
+	responses := make([]*httptest.ResponseRecorder, 2*users)
+
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := range responses {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/events/{id}/apply", nil)
+			req.SetPathValue("id", event.ID.Hex())
+			req.Header.Set("X-Email", fmt.Sprintf("user%d@example.com", i%users))
+
+			start.Wait()
+			responses[i] = httptest.NewRecorder()
+			app.applyToEventHandler(responses[i], req)
+		}()
+	}
+	start.Done()
+	done.Wait()
+
+	statuses := map[int]int{}
+	for _, rr := range responses {
+		statuses[rr.Code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusOK: capacity, http.StatusAccepted: users - capacity, http.StatusBadRequest: users}, statuses)
+
+	eventApp, err := models.EventApps.GetEventApp(context.Background(), event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, eventApp.Attendee, capacity)
+	assert.Len(t, eventApp.Waitlist, users-capacity)
+
+	stored, err := models.Event.GetEventByID(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, capacity, stored.NumberOfApplications)
+}
+
+func TestWaitlistPositionHandler(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	tests := []struct {
+		name           string
+		email          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "On Waitlist", email: "c@example.com", expectedStatus: http.StatusOK, expectedBody: `{"waitlist_position":2,"waitlist_length":3}`},
+		{name: "Not On Waitlist", email: "a@example.com", expectedStatus: http.StatusNotFound, expectedBody: `{"error":"You are not on the waitlist for this event"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventAppModel := new(MockEventAppModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			app.models = data.Models{EventApps: mockEventAppModel}
+			app.tokenExtractor = mockTokenExtractor
+
+			mockTokenExtractor.On("extractTokenData", mock.Anything).Return(tt.email, false, true, nil)
+			mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{
+				Attendee: []string{"a@example.com"},
+				Waitlist: []string{"b@example.com", "c@example.com", "d@example.com"},
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/events/{id}/waitlist", nil)
+			req.SetPathValue("id", primitive.NewObjectID().Hex())
+
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.waitlistPositionHandler)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockEventAppModel.AssertExpectations(t)
+			mockTokenExtractor.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// createEventSeries creates a recurring event: one event for each date its
+// recurrence rule gives, starting from the event's date and leaving out its
+// exdates. Each occurrence gets applications of its own.
+func (app *application) createEventSeries(w http.ResponseWriter, r *http.Request, event *data.Event) {
+	rule, err := data.ParseRRule(event.Recurrence.RRule)
+	if err != nil {
+		app.failedValidationResponse(w, r, map[string]string{"recurrence": err.Error()})
+		return
+	}
+
+	dates, err := rule.Occurrences(event.Date, event.Recurrence.ExDates)
+	if err != nil {
+		app.failedValidationResponse(w, r, map[string]string{"recurrence": err.Error()})
+		return
+	}
+
+	events, err := app.models.Event.CreateSeries(event, rule, dates)
+	if err != nil {
+		app.Logger.Printf("Error creating event series: %v", err)
+		app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to create event"}, nil)
+		return
+	}
+
+	for _, occurrence := range events {
+		err = app.models.EventApps.CreateEventApp(context.Background(), &data.EventApps{
+			ID:       primitive.NewObjectID(),
+			EventID:  occurrence.ID,
+			Attendee: []string{},
+			Waitlist: []string{},
+		})
+		if err != nil {
+			app.Logger.Printf("Error creating event app: %v", err)
+			app.deleteSeries(events)
+			app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to create event app"}, nil)
+			return
+		}
+	}
+
+	app.writeJSON(w, http.StatusCreated, envelope{"events": events}, nil)
+}
+
+// deleteSeries undoes a series that could not be created in full, removing its
+// occurrences along with the applications made for them so far. Failures are
+// only logged, as the request has failed already.
+func (app *application) deleteSeries(events []data.Event) {
+	for _, occurrence := range events {
+		err := app.models.EventApps.DeleteEventApp(context.Background(), occurrence.ID)
+		if err != nil {
+			app.Logger.Printf("Error deleting event app: %v", err)
+		}
+	}
+
+	err := app.models.Event.DeleteSeries(events[0].Recurrence.SeriesID)
+	if err != nil {
+		app.Logger.Printf("Error deleting event series: %v", err)
+	}
+}
+
+// updateEventSeries edits an occurrence of a recurring event together with the
+// ones after it (scope=following) or with the whole series (scope=all). The
+// occurrences keep their own dates, so a new date may only change the time of
+// day; anything else calls for a new series. An organizer, named by editor,
+// must organize every occurrence the edit changes.
+func (app *application) updateEventSeries(w http.ResponseWriter, r *http.Request, id primitive.ObjectID, event *data.Event, scope, editor string) {
+	if scope != data.ScopeFollowing && scope != data.ScopeAll {
+		app.failedValidationResponse(w, r, map[string]string{"scope": "must be this, following or all"})
+		return
+	}
+
+	occurrence, err := app.models.Event.GetEventByID(id)
+	if err != nil {
+		if errors.Is(err, data.ErrNoRecords) {
+			app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if occurrence.Recurrence == nil {
+		app.failedValidationResponse(w, r, map[string]string{"scope": "must be this for an event that does not recur"})
+		return
+	}
+
+	if !event.Date.IsZero() && !sameDay(event.Date, occurrence.Date) {
+		app.failedValidationResponse(w, r, map[string]string{"date": "can only change the time of day when editing several occurrences"})
+		return
+	}
+
+	events, err := app.models.Event.UpdateSeries(occurrence, event, scope, editor)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotOrganizer):
+			app.notPermittedResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.Logger.Printf("Error updating event series: %v", err)
+			app.writeJSON(w, http.StatusInternalServerError, envelope{"error": "Failed to update event"}, nil)
+		}
+		return
+	}
+
+	// The occurrences are updated either way, so their attendees are told
+	// in the background and a failure to queue that is only logged.
+	app.background(func() {
+		for _, updated := range events {
+			eventApps, err := app.models.EventApps.GetEventApp(context.Background(), updated.ID)
+			if err != nil {
+				app.Logger.Printf("Error fetching event apps: %v", err)
+				continue
+			}
+
+			if len(eventApps.Attendee) == 0 {
+				continue
+			}
+
+			app.queueEventMessage("event_update", map[string]any{
+				"emails":            eventApps.Attendee,
+				"event_name":        updated.Name,
+				"event_date":        updated.Date,
+				"event_description": updated.Description,
+			})
+		}
+	})
+
+	app.writeJSON(w, http.StatusOK, envelope{"message": "Success", "events": events}, nil)
+}
+
+// changeSeriesStatus makes a status change to an occurrence of a recurring
+// event together with the ones after it (scope=following) or with the whole
+// series (scope=all). Occurrences already past event's status are skipped.
+// Unless organizer is empty, as for an admin, it must organize every
+// occurrence changed.
+func (app *application) changeSeriesStatus(w http.ResponseWriter, r *http.Request, event *data.Event, change data.StatusChange, scope, organizer string) {
+	if event.Recurrence == nil {
+		app.failedValidationResponse(w, r, map[string]string{"scope": "must be this for an event that does not recur"})
+		return
+	}
+
+	events, err := app.models.Event.ChangeSeriesStatus(event, change, scope, organizer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotOrganizer):
+			app.notPermittedResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict), errors.Is(err, data.ErrInvalidTransition):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		for i := range events {
+			app.notifyStatusChange(&events[i])
+		}
+	})
+
+	app.writeJSON(w, http.StatusOK, envelope{"events": events}, nil)
+}
+
+// sameDay reports whether a falls on the same calendar day as b, in b's time
+// zone.
+func sameDay(a, b time.Time) bool {
+	y1, m1, d1 := a.In(b.Location()).Date()
+	y2, m2, d2 := b.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
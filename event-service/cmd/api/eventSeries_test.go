@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateEventSeries(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	// A Tuesday.
+	start := time.Date(2026, time.November, 3, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		recurrence     data.Recurrence
+		expectedStatus int
+		expectedBody   string
+		expectedEvents int
+		setupMock      func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel)
+	}{
+		{
+			name:           "Weekly Series",
+			recurrence:     data.Recurrence{RRule: "FREQ=WEEKLY;COUNT=3;BYDAY=TU", ExDates: []time.Time{start.AddDate(0, 0, 7)}},
+			expectedStatus: http.StatusCreated,
+			expectedEvents: 2,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				dates := []time.Time{start, start.AddDate(0, 0, 14)}
+				rule := mock.MatchedBy(func(rule data.RRule) bool { return rule.String() == "FREQ=WEEKLY;COUNT=3;BYDAY=TU" })
+
+				mockEventModel.On("CreateSeries", mock.AnythingOfType("*data.Event"), rule, dates).Return([]data.Event{
+					{ID: primitive.NewObjectID(), Name: "Go Workshop", Date: dates[0]},
+					{ID: primitive.NewObjectID(), Name: "Go Workshop", Date: dates[1]},
+				}, nil)
+				mockEventAppModel.On("CreateEventApp", mock.Anything, mock.AnythingOfType("*data.EventApps")).Return(nil).Times(2)
+			},
+		},
+		{
+			name:           "Applications Not Created",
+			recurrence:     data.Recurrence{RRule: "FREQ=WEEKLY;COUNT=2;BYDAY=TU"},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Failed to create event app"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				// The occurrences created before the failure are removed
+				// again, applications and all.
+				seriesID := primitive.NewObjectID()
+				events := []data.Event{
+					{ID: primitive.NewObjectID(), Recurrence: &data.Recurrence{SeriesID: seriesID}},
+					{ID: primitive.NewObjectID(), Recurrence: &data.Recurrence{SeriesID: seriesID}},
+				}
+
+				mockEventModel.On("CreateSeries", mock.AnythingOfType("*data.Event"), mock.AnythingOfType("data.RRule"), mock.Anything).Return(events, nil)
+				mockEventAppModel.On("CreateEventApp", mock.Anything, mock.AnythingOfType("*data.EventApps")).Return(nil).Once()
+				mockEventAppModel.On("CreateEventApp", mock.Anything, mock.AnythingOfType("*data.EventApps")).Return(errors.New("connection refused")).Once()
+				mockEventAppModel.On("DeleteEventApp", mock.Anything, events[0].ID).Return(nil).Once()
+				mockEventAppModel.On("DeleteEventApp", mock.Anything, events[1].ID).Return(nil).Once()
+				mockEventModel.On("DeleteSeries", seriesID).Return(nil)
+			},
+		},
+		{
+			name:           "Rule Without End",
+			recurrence:     data.Recurrence{RRule: "FREQ=WEEKLY;BYDAY=TU"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"recurrence":"must set COUNT or UNTIL"}}`,
+			setupMock:      func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Unsupported Frequency",
+			recurrence:     data.Recurrence{RRule: "FREQ=HOURLY;COUNT=3"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"recurrence":"FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY"}}`,
+			setupMock:      func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Too Many Occurrences",
+			recurrence:     data.Recurrence{RRule: "FREQ=DAILY;UNTIL=20301231"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"recurrence":"must not have more than 365 occurrences"}}`,
+			setupMock:      func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Database Error",
+			recurrence:     data.Recurrence{RRule: "FREQ=MONTHLY;COUNT=4;BYDAY=-1FR"},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"Failed to create event"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel) {
+				mockEventModel.On("CreateSeries", mock.AnythingOfType("*data.Event"), mock.AnythingOfType("data.RRule"), mock.Anything).Return([]data.Event{}, errors.New("connection refused"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventAppModel := new(MockEventAppModel)
+			mockEventModel := new(MockEventModel)
+
+			app.models = data.Models{
+				EventApps: mockEventAppModel,
+				Event:     mockEventModel,
+			}
+
+			tt.setupMock(mockEventAppModel, mockEventModel)
+
+			body, err := json.Marshal(data.Event{Name: "Go Workshop", Type: data.Workshop, Date: start, Recurrence: &tt.recurrence})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/events", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.createEventHandler)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedEvents > 0 {
+				var res struct{ Events []data.Event }
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Len(t, res.Events, tt.expectedEvents)
+			} else {
+				assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			}
+
+			mockEventAppModel.AssertExpectations(t)
+			mockEventModel.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateEventSeries(t *testing.T) {
+	date := time.Date(2026, time.November, 17, 18, 0, 0, 0, time.UTC)
+
+	occurrence := &data.Event{
+		Name: "Go Workshop",
+		Date: date,
+		Recurrence: &data.Recurrence{
+			SeriesID:   primitive.NewObjectID(),
+			RRule:      "FREQ=WEEKLY;COUNT=10;BYDAY=TU",
+			Start:      date.AddDate(0, 0, -14),
+			Occurrence: date,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		admin          bool
+		scope          string
+		date           time.Time
+		expectedStatus int
+		expectedBody   string
+		expectedEvents int
+		setupMock      func(mockEventModel *MockEventModel)
+	}{
+		{
+			name:           "Edit All",
+			scope:          data.ScopeAll,
+			date:           date.Add(time.Hour),
+			expectedStatus: http.StatusOK,
+			expectedEvents: 3,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("UpdateSeries", occurrence, mock.MatchedBy(func(event *data.Event) bool {
+					return event.Date.Equal(date.Add(time.Hour)) && event.Name == "Advanced Go Workshop"
+				}), data.ScopeAll, "john.doe@example.com").Return(make([]data.Event, 3), nil)
+			},
+		},
+		{
+			name:           "Edit Following",
+			scope:          data.ScopeFollowing,
+			date:           date,
+			expectedStatus: http.StatusOK,
+			expectedEvents: 2,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("UpdateSeries", occurrence, mock.AnythingOfType("*data.Event"), data.ScopeFollowing, "john.doe@example.com").Return(make([]data.Event, 2), nil)
+			},
+		},
+		{
+			name:           "Edit All By Admin",
+			admin:          true,
+			scope:          data.ScopeAll,
+			date:           date,
+			expectedStatus: http.StatusOK,
+			expectedEvents: 3,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("UpdateSeries", occurrence, mock.AnythingOfType("*data.Event"), data.ScopeAll, "").Return(make([]data.Event, 3), nil)
+			},
+		},
+		{
+			name:           "Occurrences Organized By Others",
+			scope:          data.ScopeAll,
+			date:           date,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"your user account doesn't have the necessary permissions to access this resource"}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("UpdateSeries", occurrence, mock.AnythingOfType("*data.Event"), data.ScopeAll, "john.doe@example.com").Return([]data.Event(nil), data.ErrNotOrganizer)
+			},
+		},
+		{
+			name:           "Every Occurrence Finished",
+			scope:          data.ScopeFollowing,
+			date:           date,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"unable to update the record due to an edit conflict, please try again"}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("UpdateSeries", occurrence, mock.AnythingOfType("*data.Event"), data.ScopeFollowing, "john.doe@example.com").Return([]data.Event(nil), data.ErrEditConflict)
+			},
+		},
+		{
+			name:           "Unknown Scope",
+			scope:          "weekends",
+			date:           date,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"scope":"must be this, following or all"}}`,
+			setupMock:      func(mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Event Does Not Recur",
+			scope:          data.ScopeAll,
+			date:           date,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"scope":"must be this for an event that does not recur"}}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Date: date}, nil)
+			},
+		},
+		{
+			name:           "Moved To Another Day",
+			scope:          data.ScopeFollowing,
+			date:           date.AddDate(0, 0, 1),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"date":"can only change the time of day when editing several occurrences"}}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+			},
+		},
+		{
+			name:           "Event Not Found",
+			scope:          data.ScopeAll,
+			date:           date,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"Event not found"}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return((*data.Event)(nil), data.ErrNoRecords)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventAppModel := new(MockEventAppModel)
+			mockEventModel := new(MockEventModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			// Each case gets an application of its own, as attendees are told
+			// about the change in the background, after the case is over.
+			app := &application{
+				Logger: log.New(io.Discard, "", 0),
+				config: config{port: "80", env: "development"},
+				models: data.Models{
+					EventApps: mockEventAppModel,
+					Event:     mockEventModel,
+				},
+				tokenExtractor: mockTokenExtractor,
+			}
+
+			if tt.admin {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+			} else {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("john.doe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+			}
+
+			mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{}, nil).Maybe()
+
+			tt.setupMock(mockEventModel)
+
+			body, err := json.Marshal(data.Event{Name: "Advanced Go Workshop", Date: tt.date})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/events/{id}?scope="+tt.scope, bytes.NewBuffer(body))
+			req.SetPathValue("id", primitive.NewObjectID().Hex())
+			req.Header.Set("Content-Type", "application/json")
+
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.updateEventHandler)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedEvents > 0 {
+				var res struct {
+					Message string
+					Events  []data.Event
+				}
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Equal(t, "Success", res.Message)
+				assert.Len(t, res.Events, tt.expectedEvents)
+			} else {
+				assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			}
+
+			mockEventModel.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// eventStatusTopics maps each status an event can move to onto the topic that
+// tells its organizers about it.
+var eventStatusTopics = map[string]string{
+	data.StatusPendingReview: "event_submitted",
+	data.StatusApproved:      "event_approved",
+	data.StatusRejected:      "event_rejected",
+	data.StatusPublished:     "event_published",
+	data.StatusCancelled:     "event_cancelled",
+	data.StatusCompleted:     "event_completed",
+}
+
+// changeEventStatusHandler returns a handler that moves an event to the given
+// status. The request body may carry a reason, which rejecting an event
+// requires. Like edits, a change can apply to the following occurrences of a
+// recurring event or to the whole series through the scope parameter. Who may
+// make which change is up to the middleware in front of it.
+func (app *application) changeEventStatusHandler(to string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := primitive.ObjectIDFromHex(r.PathValue("id"))
+		if err != nil {
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Invalid ID format"}, nil)
+			return
+		}
+
+		var input struct {
+			Reason string `json:"reason"`
+		}
+
+		if r.ContentLength != 0 {
+			err = app.readJSON(w, r, &input)
+			if err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+		}
+
+		scope := app.readString(r.URL.Query(), "scope", data.ScopeThis)
+
+		switch {
+		case scope != data.ScopeThis && scope != data.ScopeFollowing && scope != data.ScopeAll:
+			app.failedValidationResponse(w, r, map[string]string{"scope": "must be this, following or all"})
+			return
+		case scope != data.ScopeThis && to == data.StatusCompleted:
+			app.failedValidationResponse(w, r, map[string]string{"scope": "must be this when completing an event"})
+			return
+		case to == data.StatusRejected && input.Reason == "":
+			app.failedValidationResponse(w, r, map[string]string{"reason": "must be provided"})
+			return
+		case len(input.Reason) > 500:
+			app.failedValidationResponse(w, r, map[string]string{"reason": "must not be more than 500 bytes long"})
+			return
+		}
+
+		email, roles, err := app.tokenExtractor.extractIdentity(r)
+		if err != nil {
+			app.writeJSON(w, http.StatusUnauthorized, envelope{"error": "Invalid token"}, nil)
+			return
+		}
+
+		event, err := app.models.Event.GetEventByID(id)
+		if err != nil {
+			if errors.Is(err, data.ErrNoRecords) {
+				app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
+				return
+			}
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !data.CanTransition(event.Status, to) {
+			app.errorResponse(w, r, http.StatusConflict, fmt.Sprintf("a %s event cannot be moved to %s", event.Status, to))
+			return
+		}
+
+		if to == data.StatusCompleted && event.Date.After(time.Now()) {
+			app.errorResponse(w, r, http.StatusConflict, "the event has not taken place yet")
+			return
+		}
+
+		change := data.StatusChange{
+			From:   event.Status,
+			To:     to,
+			Reason: input.Reason,
+			By:     email,
+			At:     time.Now(),
+		}
+
+		if scope != data.ScopeThis {
+			// The middleware only checked the occurrence in the URL, and the
+			// others may have organizers of their own.
+			organizer := email
+			if app.Contains(roles, data.RoleAdmin) {
+				organizer = ""
+			}
+
+			app.changeSeriesStatus(w, r, event, change, scope, organizer)
+			return
+		}
+
+		updated, err := app.models.Event.ChangeStatus(id, change)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict), errors.Is(err, data.ErrInvalidTransition):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		// The change is made either way, so the emails go out in the
+		// background and a failure to queue them is only logged.
+		app.background(func() {
+			app.notifyStatusChange(updated)
+		})
+
+		app.writeJSON(w, http.StatusOK, envelope{"event": updated}, nil)
+	}
+}
+
+// notifyStatusChange emails the event's organizers about its new status.
+// Publishing an event also announces it to subscribers, and cancelling it
+// tells everyone who applied or is on the waitlist.
+func (app *application) notifyStatusChange(event *data.Event) {
+	organizers := make([]string, 0, len(event.Organizers))
+	for _, organizer := range event.Organizers {
+		organizers = append(organizers, organizer.Email)
+	}
+
+	app.queueEventMessage(eventStatusTopics[event.Status], map[string]any{
+		"emails":     organizers,
+		"event_id":   event.ID.Hex(),
+		"event_name": event.Name,
+		"event_date": event.Date,
+		"status":     event.Status,
+		"reason":     event.StatusReason,
+	})
+
+	switch event.Status {
+	case data.StatusPublished:
+		app.queueEventMessage("event_add", map[string]any{
+			"event_type":        event.Type,
+			"event_name":        event.Name,
+			"event_date":        event.Date,
+			"event_description": event.Description,
+			"event_location":    fmt.Sprintf("%s,\n%s,\n%s,\n%s", event.Location.Address, event.Location.City, event.Location.State, event.Location.Country),
+		})
+
+	case data.StatusCancelled:
+		eventApps, err := app.models.EventApps.GetEventApp(context.Background(), event.ID)
+		if err != nil {
+			app.Logger.Printf("Error fetching event apps: %v", err)
+			return
+		}
+
+		app.queueEventMessage("event_remove", map[string]any{
+			"emails":     append(eventApps.Attendee, eventApps.Waitlist...),
+			"event_name": event.Name,
+			"event_date": event.Date,
+		})
+	}
+}
+
+func (app *application) queueEventMessage(topic string, payload map[string]any) {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		app.Logger.Printf("Error marshaling payload: %v", err)
+		return
+	}
+
+	err = app.pushToQueue(topic, string(jsonPayload))
+	if err != nil {
+		app.Logger.Printf("Error pushing event to queue: %v", err)
+	}
+}
+
+// eventVisibility narrows filters down to the events the requester may see.
+// Admins see every event and other users see published events along with
+// the ones they organize. Anyone without valid credentials, students
+// included, only sees published events.
+func (app *application) eventVisibility(r *http.Request, filters *data.EventFilters) {
+	filters.PublishedOnly = true
+
+	if r.Header.Get("Authorization") == "" && apiKeyFromRequest(r) == "" {
+		return
+	}
+
+	email, roles, err := app.tokenExtractor.extractIdentity(r)
+	if err != nil {
+		return
+	}
+
+	if app.Contains(roles, data.RoleAdmin) {
+		filters.PublishedOnly = false
+		return
+	}
+
+	filters.Viewer = email
+}
+
+// canViewEvent reports whether the requester may see the event, by the same
+// rules as eventVisibility.
+func (app *application) canViewEvent(r *http.Request, event *data.Event) bool {
+	if event.Status == data.StatusPublished {
+		return true
+	}
+
+	var filters data.EventFilters
+	app.eventVisibility(r, &filters)
+
+	return !filters.PublishedOnly || (filters.Viewer != "" && event.IsOrganizer(filters.Viewer))
+}
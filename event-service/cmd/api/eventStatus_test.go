@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestChangeEventStatus(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	upcoming := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name           string
+		to             string
+		scope          string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		expectedEvent  *data.Event
+		expectedEvents int
+		setupMock      func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor)
+	}{
+		{
+			name:           "Submit Draft",
+			to:             data.StatusPendingReview,
+			expectedStatus: http.StatusOK,
+			expectedEvent:  &data.Event{Status: data.StatusPendingReview},
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("john.doe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: data.StatusDraft, Date: upcoming}, nil)
+				mockEventModel.On("ChangeStatus", mock.AnythingOfType("primitive.ObjectID"), mock.MatchedBy(func(c data.StatusChange) bool {
+					return c.From == data.StatusDraft && c.To == data.StatusPendingReview && c.By == "john.doe@example.com"
+				})).Return(&data.Event{Status: data.StatusPendingReview}, nil)
+			},
+		},
+		{
+			name:           "Approve Legacy Pending Event",
+			to:             data.StatusApproved,
+			expectedStatus: http.StatusOK,
+			expectedEvent:  &data.Event{Status: data.StatusApproved},
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: "PENDING", Date: upcoming}, nil)
+				mockEventModel.On("ChangeStatus", mock.AnythingOfType("primitive.ObjectID"), mock.MatchedBy(func(c data.StatusChange) bool {
+					return c.From == "PENDING" && c.To == data.StatusApproved
+				})).Return(&data.Event{Status: data.StatusApproved}, nil)
+			},
+		},
+		{
+			name:           "Reject With Reason",
+			to:             data.StatusRejected,
+			body:           `{"reason": "Pick a larger venue"}`,
+			expectedStatus: http.StatusOK,
+			expectedEvent:  &data.Event{Status: data.StatusRejected, StatusReason: "Pick a larger venue"},
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: data.StatusPendingReview, Date: upcoming}, nil)
+				mockEventModel.On("ChangeStatus", mock.AnythingOfType("primitive.ObjectID"), mock.MatchedBy(func(c data.StatusChange) bool {
+					return c.To == data.StatusRejected && c.Reason == "Pick a larger venue"
+				})).Return(&data.Event{Status: data.StatusRejected, StatusReason: "Pick a larger venue"}, nil)
+			},
+		},
+		{
+			name:           "Reject Without Reason",
+			to:             data.StatusRejected,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"reason":"must be provided"}}`,
+			setupMock:      func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {},
+		},
+		{
+			name:           "Approve Draft",
+			to:             data.StatusApproved,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"a DRAFT event cannot be moved to APPROVED"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: data.StatusDraft, Date: upcoming}, nil)
+			},
+		},
+		{
+			name:           "Complete Upcoming Event",
+			to:             data.StatusCompleted,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"the event has not taken place yet"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("john.doe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: data.StatusPublished, Date: upcoming}, nil)
+			},
+		},
+		{
+			name:           "Reviewed Concurrently",
+			to:             data.StatusApproved,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"unable to update the record due to an edit conflict, please try again"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: data.StatusPendingReview, Date: upcoming}, nil)
+				mockEventModel.On("ChangeStatus", mock.AnythingOfType("primitive.ObjectID"), mock.AnythingOfType("data.StatusChange")).Return((*data.Event)(nil), data.ErrEditConflict)
+			},
+		},
+		{
+			name:           "Approve Whole Series",
+			to:             data.StatusApproved,
+			scope:          data.ScopeAll,
+			expectedStatus: http.StatusOK,
+			expectedEvents: 3,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				occurrence := &data.Event{Status: data.StatusPendingReview, Date: upcoming, Recurrence: &data.Recurrence{SeriesID: primitive.NewObjectID()}}
+
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("ChangeSeriesStatus", occurrence, mock.MatchedBy(func(c data.StatusChange) bool {
+					return c.From == data.StatusPendingReview && c.To == data.StatusApproved && c.By == "admin@example.com"
+				}), data.ScopeAll, "").Return(make([]data.Event, 3), nil)
+			},
+		},
+		{
+			name:           "Series Already Reviewed",
+			to:             data.StatusApproved,
+			scope:          data.ScopeFollowing,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"error":"unable to update the record due to an edit conflict, please try again"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				occurrence := &data.Event{Status: data.StatusPendingReview, Date: upcoming, Recurrence: &data.Recurrence{SeriesID: primitive.NewObjectID()}}
+
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("ChangeSeriesStatus", occurrence, mock.AnythingOfType("data.StatusChange"), data.ScopeFollowing, "").Return([]data.Event(nil), data.ErrEditConflict)
+			},
+		},
+		{
+			name:           "Publish Series Organized By Others",
+			to:             data.StatusPublished,
+			scope:          data.ScopeAll,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"your user account doesn't have the necessary permissions to access this resource"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				occurrence := &data.Event{Status: data.StatusApproved, Date: upcoming, Recurrence: &data.Recurrence{SeriesID: primitive.NewObjectID()}}
+
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("john.doe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("ChangeSeriesStatus", occurrence, mock.AnythingOfType("data.StatusChange"), data.ScopeAll, "john.doe@example.com").Return([]data.Event(nil), data.ErrNotOrganizer)
+			},
+		},
+		{
+			name:           "Series Scope For Single Event",
+			to:             data.StatusApproved,
+			scope:          data.ScopeAll,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"scope":"must be this for an event that does not recur"}}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{Status: data.StatusPendingReview, Date: upcoming}, nil)
+			},
+		},
+		{
+			name:           "Complete Whole Series",
+			to:             data.StatusCompleted,
+			scope:          data.ScopeAll,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"scope":"must be this when completing an event"}}`,
+			setupMock:      func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {},
+		},
+		{
+			name:           "Unknown Scope",
+			to:             data.StatusApproved,
+			scope:          "weekends",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error":{"scope":"must be this, following or all"}}`,
+			setupMock:      func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {},
+		},
+		{
+			name:           "Event Not Found",
+			to:             data.StatusPublished,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"Event not found"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return((*data.Event)(nil), data.ErrNoRecords)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventModel := new(MockEventModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			app.models = data.Models{
+				EventApps: new(MockEventAppModel),
+				Event:     mockEventModel,
+			}
+			app.tokenExtractor = mockTokenExtractor
+
+			tt.setupMock(mockEventModel, mockTokenExtractor)
+
+			var body io.Reader = http.NoBody
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/events/{id}/status?scope="+tt.scope, body)
+			req.SetPathValue("id", primitive.NewObjectID().Hex())
+
+			rr := httptest.NewRecorder()
+
+			handler := app.changeEventStatusHandler(tt.to)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedEvent != nil {
+				var res struct{ Event data.Event }
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Equal(t, *tt.expectedEvent, res.Event)
+			} else if tt.expectedEvents > 0 {
+				var res struct{ Events []data.Event }
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Len(t, res.Events, tt.expectedEvents)
+			} else {
+				assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			}
+
+			mockEventModel.AssertExpectations(t)
+			mockTokenExtractor.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEventVisibility(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	draft := &data.Event{
+		Status:     data.StatusDraft,
+		Organizers: []data.Organizer{{Name: "John Doe", Email: "john.doe@example.com"}},
+	}
+
+	tests := []struct {
+		name            string
+		token           bool
+		email           string
+		roles           []string
+		expectedFilters data.EventFilters
+		expectedStatus  int
+	}{
+		{
+			name:            "Anonymous",
+			expectedFilters: data.EventFilters{PublishedOnly: true},
+			expectedStatus:  http.StatusNotFound,
+		},
+		{
+			name:            "Student",
+			token:           true,
+			email:           "student@example.com",
+			roles:           []string{data.RoleAttendee},
+			expectedFilters: data.EventFilters{PublishedOnly: true, Viewer: "student@example.com"},
+			expectedStatus:  http.StatusNotFound,
+		},
+		{
+			name:            "Organizer",
+			token:           true,
+			email:           "john.doe@example.com",
+			roles:           []string{data.RoleOrganizer},
+			expectedFilters: data.EventFilters{PublishedOnly: true, Viewer: "john.doe@example.com"},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "Admin",
+			token:           true,
+			email:           "admin@example.com",
+			roles:           []string{data.RoleAdmin},
+			expectedFilters: data.EventFilters{},
+			expectedStatus:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventModel := new(MockEventModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			app.models = data.Models{Event: mockEventModel}
+			app.tokenExtractor = mockTokenExtractor
+
+			if tt.token {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return(tt.email, false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return(tt.roles, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/events/", nil)
+			if tt.token {
+				req.Header.Set("Authorization", "Bearer token")
+			}
+
+			var filters data.EventFilters
+			app.eventVisibility(req, &filters)
+			assert.Equal(t, tt.expectedFilters, filters)
+
+			mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(draft, nil)
+
+			req.SetPathValue("id", primitive.NewObjectID().Hex())
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.getEventByIDHandler)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+
+			mockEventModel.AssertExpectations(t)
+		})
+	}
+}
@@ -33,8 +33,8 @@ func (m *MockEventModel) CreateEvent(event *data.Event) (*data.Event, error) {
 	return args.Get(0).(*data.Event), args.Error(1)
 }
 
-func (m *MockEventModel) UpdateEvent(id primitive.ObjectID, event *data.Event) (*data.Event, error) {
-	args := m.Called(id, event)
+func (m *MockEventModel) UpdateEvent(id primitive.ObjectID, event *data.Event, editor string) (*data.Event, error) {
+	args := m.Called(id, event, editor)
 	return args.Get(0).(*data.Event), args.Error(1)
 
 }
@@ -49,6 +49,46 @@ func (m *MockEventModel) GetAllEvents() ([]data.Event, error) {
 	return args.Get(0).([]data.Event), args.Error(1)
 }
 
+func (m *MockEventModel) SearchEvents(query string, filters data.EventFilters) ([]data.SearchResult, data.Metadata, error) {
+	args := m.Called(query, filters)
+	return args.Get(0).([]data.SearchResult), args.Get(1).(data.Metadata), args.Error(2)
+}
+
+func (m *MockEventModel) ChangeStatus(id primitive.ObjectID, change data.StatusChange) (*data.Event, error) {
+	args := m.Called(id, change)
+	return args.Get(0).(*data.Event), args.Error(1)
+}
+
+func (m *MockEventModel) CreateSeries(event *data.Event, rule data.RRule, dates []time.Time) ([]data.Event, error) {
+	args := m.Called(event, rule, dates)
+	return args.Get(0).([]data.Event), args.Error(1)
+}
+
+func (m *MockEventModel) DeleteSeries(seriesID primitive.ObjectID) error {
+	args := m.Called(seriesID)
+	return args.Error(0)
+}
+
+func (m *MockEventModel) ChangeSeriesStatus(occurrence *data.Event, change data.StatusChange, scope, organizer string) ([]data.Event, error) {
+	args := m.Called(occurrence, change, scope, organizer)
+	return args.Get(0).([]data.Event), args.Error(1)
+}
+
+func (m *MockEventModel) UpdateSeries(occurrence *data.Event, event *data.Event, scope, editor string) ([]data.Event, error) {
+	args := m.Called(occurrence, event, scope, editor)
+	return args.Get(0).([]data.Event), args.Error(1)
+}
+
+func (m *MockEventModel) ExcludeOccurrence(occurrence *data.Event) error {
+	args := m.Called(occurrence)
+	return args.Error(0)
+}
+
+func (m *MockEventModel) ListEvents(filters data.EventFilters) ([]data.Event, data.Metadata, error) {
+	args := m.Called(filters)
+	return args.Get(0).([]data.Event), args.Get(1).(data.Metadata), args.Error(2)
+}
+
 func TestGetEventByID(t *testing.T) {
 	mockEventAppModel := new(MockEventAppModel)
 	mockEventModel := new(MockEventModel)
@@ -110,7 +150,7 @@ func TestGetEventByID(t *testing.T) {
 							"role": "Lead Organizer"
 						}
 					],
-					"status": "PENDING",
+					"status": "PUBLISHED",
 					"type": "CONFERENCE",
 					"updated_at": "2024-11-27T15:31:01.393Z",
 					"ushers": [
@@ -149,7 +189,7 @@ func TestGetEventByID(t *testing.T) {
 							Role:  "Lead Organizer",
 						},
 					},
-					Status:    "PENDING",
+					Status:    "PUBLISHED",
 					Type:      "CONFERENCE",
 					UpdatedAt: updatedAt,
 					Ushers:    []string{"Alice Smith", "Bob Johnson"},
@@ -206,6 +246,271 @@ func TestGetEventByID(t *testing.T) {
 	}
 }
 
+func TestGetAllEvents(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	from, _ := time.Parse(time.RFC3339, "2024-07-01T00:00:00Z")
+	to, _ := time.Parse(time.RFC3339, "2024-07-31T23:59:59Z")
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+		setupMock      func(mockEventModel *MockEventModel)
+	}{
+		{
+			name:           "Defaults",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"events": [], "metadata": {"total_records": 0}}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("ListEvents", data.EventFilters{
+					Sort:          "date",
+					Direction:     "asc",
+					Page:          1,
+					PageSize:      20,
+					PublishedOnly: true,
+				}).Return([]data.Event{}, data.Metadata{}, nil)
+			},
+		},
+		{
+			name:           "All Filters",
+			query:          "?type=workshop&status=published&city=Cairo&country=Egypt&organizer_email=jane@example.com&from=2024-07-01&to=2024-07-31T23:59:59Z&sort=name&direction=DESC&page=2&page_size=5",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+				"events": [{
+					"_id": "000000000000000000000000",
+					"created_at": "0001-01-01T00:00:00Z",
+					"date": "0001-01-01T00:00:00Z",
+					"description": "",
+					"location": {"address": "", "city": "Cairo", "country": "Egypt", "state": ""},
+					"max_capacity": 0,
+					"min_capacity": 0,
+					"name": "Go Workshop",
+					"number_of_applications": 0,
+					"organizers": null,
+					"status": "PUBLISHED",
+					"type": "WORKSHOP",
+					"updated_at": "0001-01-01T00:00:00Z",
+					"ushers": null
+				}],
+				"metadata": {"current_page": 2, "page_size": 5, "first_page": 1, "last_page": 2, "total_records": 6}
+			}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("ListEvents", data.EventFilters{
+					Type:           data.Workshop,
+					Status:         "PUBLISHED",
+					City:           "Cairo",
+					Country:        "Egypt",
+					OrganizerEmail: "jane@example.com",
+					From:           from,
+					To:             to,
+					Sort:           "name",
+					Direction:      "desc",
+					Page:           2,
+					PageSize:       5,
+					PublishedOnly:  true,
+				}).Return([]data.Event{{
+					Name:     "Go Workshop",
+					Type:     data.Workshop,
+					Status:   "PUBLISHED",
+					Location: data.Location{City: "Cairo", Country: "Egypt"},
+				}}, data.Metadata{CurrentPage: 2, PageSize: 5, FirstPage: 1, LastPage: 2, TotalRecords: 6}, nil)
+			},
+		},
+		{
+			name:           "Invalid Parameters",
+			query:          "?type=party&status=pending&series_id=weekly&from=yesterday&sort=password&direction=up&page=zero&page_size=1000",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `{"error": {
+				"type": "must be one of CONFERENCE, WORKSHOP, MEETUP, SOCIAL, CAREER_FAIR, GRADUATION or OTHER",
+				"status": "must be one of DRAFT, PENDING_REVIEW, APPROVED, REJECTED, PUBLISHED, CANCELLED or COMPLETED",
+				"series_id": "must be a valid ID",
+				"from": "must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+				"sort": "must be one of date, name, created_at or max_capacity",
+				"direction": "must be asc or desc",
+				"page": "must be an integer value",
+				"page_size": "must be between 1 and 100"
+			}}`,
+			setupMock: func(mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Series Occurrences In Range",
+			query:          "?series_id=67689dee645ad5b4bbbe122d&from=2024-07-01&to=2024-07-31T23:59:59Z",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"events": [], "metadata": {"total_records": 0}}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				seriesID, _ := primitive.ObjectIDFromHex("67689dee645ad5b4bbbe122d")
+				mockEventModel.On("ListEvents", data.EventFilters{
+					SeriesID:      seriesID,
+					From:          from,
+					To:            to,
+					Sort:          "date",
+					Direction:     "asc",
+					Page:          1,
+					PageSize:      20,
+					PublishedOnly: true,
+				}).Return([]data.Event{}, data.Metadata{}, nil)
+			},
+		},
+		{
+			name:           "Date Range Reversed",
+			query:          "?from=2024-08-01&to=2024-07-01",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"to": "must not be before from"}}`,
+			setupMock:      func(mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Database Error",
+			query:          "",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "Failed to fetch events"}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("ListEvents", mock.AnythingOfType("data.EventFilters")).Return([]data.Event{}, data.Metadata{}, errors.New("connection refused"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventModel := new(MockEventModel)
+
+			app.models = data.Models{Event: mockEventModel}
+
+			tt.setupMock(mockEventModel)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/events/"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.getAllEventsHandler)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockEventModel.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSearchEvents(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	defaultFilters := data.EventFilters{Sort: "date", Direction: "asc", Page: 1, PageSize: 20, PublishedOnly: true}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+		setupMock      func(mockEventModel *MockEventModel)
+	}{
+		{
+			name:           "Matching Events",
+			query:          "?q=confrence",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+				"results": [{
+					"event": {
+						"_id": "000000000000000000000000",
+						"created_at": "0001-01-01T00:00:00Z",
+						"date": "0001-01-01T00:00:00Z",
+						"description": "",
+						"location": {"address": "", "city": "", "country": "", "state": ""},
+						"max_capacity": 0,
+						"min_capacity": 0,
+						"name": "Tech Conference",
+						"number_of_applications": 0,
+						"organizers": null,
+						"status": "",
+						"type": "",
+						"updated_at": "0001-01-01T00:00:00Z",
+						"ushers": null
+					},
+					"score": 2.25,
+					"highlights": {"name": "Tech <mark>Conference</mark>"}
+				}],
+				"metadata": {"current_page": 1, "page_size": 20, "first_page": 1, "last_page": 1, "total_records": 1}
+			}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("SearchEvents", "confrence", defaultFilters).Return([]data.SearchResult{{
+					Event:      data.Event{Name: "Tech Conference"},
+					Score:      2.25,
+					Highlights: map[string]any{"name": "Tech <mark>Conference</mark>"},
+				}}, data.Metadata{CurrentPage: 1, PageSize: 20, FirstPage: 1, LastPage: 1, TotalRecords: 1}, nil)
+			},
+		},
+		{
+			name:           "With Filters",
+			query:          "?q=go&type=workshop&city=Cairo&page_size=5",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"results": [], "metadata": {"total_records": 0}}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("SearchEvents", "go", data.EventFilters{
+					Type:          data.Workshop,
+					City:          "Cairo",
+					Sort:          "date",
+					Direction:     "asc",
+					Page:          1,
+					PageSize:      5,
+					PublishedOnly: true,
+				}).Return([]data.SearchResult{}, data.Metadata{}, nil)
+			},
+		},
+		{
+			name:           "Missing Query",
+			query:          "?type=workshop",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"q": "must be provided"}}`,
+			setupMock:      func(mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Query Without Words",
+			query:          "?q=%2B%2B%2B&page=0",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"q": "must contain at least one letter or digit", "page": "must be between 1 and 10 million"}}`,
+			setupMock:      func(mockEventModel *MockEventModel) {},
+		},
+		{
+			name:           "Database Error",
+			query:          "?q=conference",
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error": "Failed to search events"}`,
+			setupMock: func(mockEventModel *MockEventModel) {
+				mockEventModel.On("SearchEvents", "conference", defaultFilters).Return([]data.SearchResult{}, data.Metadata{}, errors.New("connection refused"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventModel := new(MockEventModel)
+
+			app.models = data.Models{Event: mockEventModel}
+
+			tt.setupMock(mockEventModel)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/events/search"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler := http.HandlerFunc(app.searchEventsHandler)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockEventModel.AssertExpectations(t)
+		})
+	}
+}
+
 func TestCreateEvent(t *testing.T) {
 	mockEventAppModel := new(MockEventAppModel)
 	mockEventModel := new(MockEventModel)
@@ -432,9 +737,26 @@ func TestDeleteEvent(t *testing.T) {
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
 				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{}, nil)
 				mockEventModel.On("DeleteEvent", mock.Anything).Return(nil)
+				mockEventAppModel.On("DeleteEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(nil)
 
 			},
 		},
+		{
+			name: "Delete Occurrence Of Series",
+			eventApp: struct {
+				EventID string
+			}{
+				EventID: primitive.NewObjectID().Hex(),
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Event deleted successfully"}`,
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				occurrence := &data.Event{Recurrence: &data.Recurrence{SeriesID: primitive.NewObjectID(), RRule: "FREQ=WEEKLY;COUNT=10;BYDAY=TU"}}
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(occurrence, nil)
+				mockEventModel.On("ExcludeOccurrence", occurrence).Return(nil)
+				mockEventAppModel.On("DeleteEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(nil)
+			},
+		},
 		{
 
 			name: "Invalid ID Format",
@@ -578,8 +900,9 @@ func TestUpdateEventHandler(t *testing.T) {
 			expectedBody:   `{"message":"Success"}`,
 
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
-				// mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.Anything).Return(&data.Event{}, nil)
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("johndoe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.Anything, "johndoe@example.com").Return(&data.Event{}, nil)
 				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{}, nil)
 			},
 		},
@@ -656,8 +979,9 @@ func TestUpdateEventHandler(t *testing.T) {
 			expectedBody:   `{"error": "Failed to update event"}`,
 
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
-				// mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.AnythingOfType("*data.Event")).Return(&data.Event{}, errors.New("ERROR"))
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("johndoe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.AnythingOfType("*data.Event"), "johndoe@example.com").Return(&data.Event{}, errors.New("ERROR"))
 
 			},
 		},
@@ -696,12 +1020,33 @@ func TestUpdateEventHandler(t *testing.T) {
 			expectedBody:   `{"error": "the server encountered a problem and could not process your request"}`,
 
 			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
-				// mockTokenExtractor.On("extractTokenData", mock.Anything).Return("test@example.com", true, true, nil)
-				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.AnythingOfType("*data.Event")).Return(&data.Event{}, nil)
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("johndoe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleOrganizer}, nil)
+				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.AnythingOfType("*data.Event"), "johndoe@example.com").Return(&data.Event{}, nil)
 				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{}, data.ErrNoRecords)
 
 			},
 		},
+		{
+			name:    "Edit By Admin",
+			eventId: primitive.NewObjectID().Hex(),
+			eventData: data.Event{
+				Date: time.Date(2025, 7, 15, 18, 0, 0, 0, time.UTC),
+				Type: "CONFERENCE",
+				Name: "Tech Conference 2025",
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Success"}`,
+
+			setupMock: func(mockEventAppModel *MockEventAppModel, mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				// Admins review events themselves, so their edits do not send
+				// the event back for review.
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{data.RoleAdmin}, nil)
+				mockEventModel.On("UpdateEvent", mock.AnythingOfType("primitive.ObjectID"), mock.AnythingOfType("*data.Event"), "").Return(&data.Event{}, nil)
+				mockEventAppModel.On("GetEventApp", mock.Anything, mock.AnythingOfType("primitive.ObjectID")).Return(&data.EventApps{}, nil)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/MohamedHossam2004/Event-Planner/event-service/rabbit"
 	"github.com/pascaldekloe/jwt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type envelope map[string]any
@@ -22,12 +26,32 @@ type payload struct {
 
 type TokenExtractor interface {
 	extractTokenData(r *http.Request) (string, bool, bool, error)
+	extractRoles(r *http.Request) ([]string, error)
+	// extractIdentity returns the caller's email and roles, verifying the
+	// credential only once.
+	extractIdentity(r *http.Request) (string, []string, error)
 }
 
 type realTokenExtractor struct {
-	jwtSecret string
+	keys        *jwksCache
+	denylistURL string
+	apiKeyURL   string
+	client      *http.Client
+
+	// secret is the TOKEN_INTROSPECTION_SECRET the authentication service
+	// asks for before it verifies an API key.
+	secret string
+
+	// introspector, when set, replaces local JWT checks and the API key and
+	// denylist lookups with a single cached call to the authentication
+	// service.
+	introspector *introspector
 }
 
+// API keys are told apart from JWTs by this prefix when they are sent as a
+// Bearer token instead of in the X-API-Key header.
+const apiKeyPrefix = "geh_"
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -98,6 +122,72 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+// readInt records a malformed value in errors under key and returns the
+// default.
+func (app *application) readInt(qs url.Values, key string, defaultValue int, errors map[string]string) int {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		errors[key] = "must be an integer value"
+		return defaultValue
+	}
+
+	return i
+}
+
+// readTime accepts an RFC 3339 timestamp or a plain date, which is taken as
+// midnight UTC. A malformed value is recorded in errors under key.
+func (app *application) readTime(qs url.Values, key string, errors map[string]string) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t
+		}
+	}
+
+	errors[key] = "must be an RFC 3339 timestamp or a YYYY-MM-DD date"
+	return time.Time{}
+}
+
+// readObjectID reads an ID in its hex form. A malformed value is recorded in
+// errors under key.
+func (app *application) readObjectID(qs url.Values, key string, errors map[string]string) primitive.ObjectID {
+	s := qs.Get(key)
+
+	if s == "" {
+		return primitive.NilObjectID
+	}
+
+	id, err := primitive.ObjectIDFromHex(s)
+	if err != nil {
+		errors[key] = "must be a valid ID"
+		return primitive.NilObjectID
+	}
+
+	return id
+}
+
 func (app *application) pushToQueue(name, msg string) error {
 	emitter, err := rabbit.NewEventEmitter(app.Rabbit)
 	if err != nil {
@@ -142,23 +232,43 @@ func (app *application) background(fn func()) {
 }
 
 func (rte *realTokenExtractor) extractTokenData(r *http.Request) (string, bool, bool, error) {
-	token := r.Header.Get("Authorization")
+	claims, err := rte.verify(r)
+	if err != nil {
+		return "", false, false, err
+	}
 
-	if token == "" {
-		return "", false, false, errors.New("missing authorization header")
+	return tokenData(claims)
+}
+
+func (rte *realTokenExtractor) extractRoles(r *http.Request) ([]string, error) {
+	claims, err := rte.verify(r)
+	if err != nil {
+		return nil, err
 	}
 
-	token = strings.TrimSpace(strings.Replace(token, "Bearer", "", 1))
+	return tokenRoles(claims)
+}
 
-	claims, err := jwt.HMACCheck([]byte(token), []byte(rte.jwtSecret))
+func (rte *realTokenExtractor) extractIdentity(r *http.Request) (string, []string, error) {
+	claims, err := rte.verify(r)
 	if err != nil {
-		return "", false, false, err
+		return "", nil, err
 	}
 
-	if !claims.Valid(time.Now()) {
-		return "", false, false, errors.New("invalid token")
+	email, _, _, err := tokenData(claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	roles, err := tokenRoles(claims)
+	if err != nil {
+		return "", nil, err
 	}
 
+	return email, roles, nil
+}
+
+func tokenData(claims *jwt.Claims) (string, bool, bool, error) {
 	userEmail, ok := claims.Set["email"].(string)
 	if !ok {
 		return "", false, false, errors.New("invalid token")
@@ -177,6 +287,221 @@ func (rte *realTokenExtractor) extractTokenData(r *http.Request) (string, bool,
 	return userEmail, role, isActivated, nil
 }
 
+func tokenRoles(claims *jwt.Claims) ([]string, error) {
+	values, ok := claims.Set["roles"].([]any)
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		role, ok := value.(string)
+		if !ok {
+			return nil, errors.New("invalid token")
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+func (rte *realTokenExtractor) verify(r *http.Request) (*jwt.Claims, error) {
+	if rte.introspector != nil {
+		return rte.introspect(r)
+	}
+
+	if key := apiKeyFromRequest(r); key != "" {
+		return rte.verifyAPIKey(r, key)
+	}
+
+	token := r.Header.Get("Authorization")
+
+	if token == "" {
+		return nil, errors.New("missing authorization header")
+	}
+
+	token = strings.TrimSpace(strings.Replace(token, "Bearer", "", 1))
+
+	claims, err := rte.keys.check([]byte(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.Valid(time.Now()) {
+		return nil, errors.New("invalid token")
+	}
+
+	revoked, err := rte.isRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer"))
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return token
+	}
+
+	return ""
+}
+
+// verifyAPIKey resolves an API key through the authentication service and
+// returns claims shaped like those of a JWT, so callers need not care which
+// kind of credential was used. Keys are limited to their scopes: reads need
+// events:read and everything else needs events:write.
+func (rte *realTokenExtractor) verifyAPIKey(r *http.Request, key string) (*jwt.Claims, error) {
+	body, err := json.Marshal(map[string]string{"key": key})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, rte.apiKeyURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+rte.secret)
+
+	response, err := rte.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("invalid api key")
+	}
+
+	var result struct {
+		APIKey struct {
+			Scopes []string `json:"scopes"`
+		} `json:"api_key"`
+		User struct {
+			Email     string `json:"email"`
+			IsAdmin   bool   `json:"is_admin"`
+			Activated bool   `json:"activated"`
+		} `json:"user"`
+		Roles []string `json:"roles"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = requireScope(r, result.APIKey.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]any, len(result.Roles))
+	for i, role := range result.Roles {
+		roles[i] = role
+	}
+
+	var claims jwt.Claims
+	claims.Set = map[string]any{
+		"email":       result.User.Email,
+		"isAdmin":     result.User.IsAdmin,
+		"isActivated": result.User.Activated,
+		"roles":       roles,
+	}
+
+	return &claims, nil
+}
+
+// introspect verifies the request's JWT or API key through the introspection
+// endpoint. API keys are held to their scopes as in verifyAPIKey.
+func (rte *realTokenExtractor) introspect(r *http.Request) (*jwt.Claims, error) {
+	key := apiKeyFromRequest(r)
+
+	token := key
+	if token == "" {
+		token = strings.TrimSpace(strings.Replace(r.Header.Get("Authorization"), "Bearer", "", 1))
+	}
+
+	if token == "" {
+		return nil, errors.New("missing authorization header")
+	}
+
+	claims, err := rte.introspector.check(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if scopes, ok := claims.Set["scopes"].([]any); ok {
+		names := make([]string, 0, len(scopes))
+		for _, scope := range scopes {
+			if name, ok := scope.(string); ok {
+				names = append(names, name)
+			}
+		}
+
+		err = requireScope(r, names)
+		if err != nil {
+			return nil, err
+		}
+	} else if key != "" {
+		return nil, errors.New("invalid api key")
+	}
+
+	return claims, nil
+}
+
+// requireScope checks that an API key may be used for the request: reads need
+// events:read and everything else needs events:write.
+func requireScope(r *http.Request, scopes []string) error {
+	scope := "events:write"
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = "events:read"
+	}
+
+	for _, s := range scopes {
+		if s == scope {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("api key is missing the %s scope", scope)
+}
+
+// isRevoked asks the authentication service whether the token with the given
+// jti has been logged out before its expiry.
+func (rte *realTokenExtractor) isRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, errors.New("invalid token")
+	}
+
+	response, err := rte.client.Get(rte.denylistURL + "/" + url.PathEscape(jti))
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d from authentication service", response.StatusCode)
+	}
+
+	var body struct {
+		Revoked bool `json:"revoked"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		return false, err
+	}
+
+	return body.Revoked, nil
+}
+
 func (app *application) Contains(slice []string, item string) bool {
 	for _, v := range slice {
 		if v == item {
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestExtractTokenDataWithAPIKey(t *testing.T) {
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Key string `json:"key"`
+		}
+		app := &application{}
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := app.readJSON(w, r, &input); err != nil || input.Key != "geh_valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		app.writeJSON(w, http.StatusOK, envelope{
+			"api_key": envelope{"scopes": []string{"events:read"}},
+			"user":    envelope{"email": "board@example.com", "is_admin": false, "activated": true},
+			"roles":   []string{"organizer"},
+		}, nil)
+	}))
+	defer authService.Close()
+
+	rte := &realTokenExtractor{
+		apiKeyURL: authService.URL,
+		client:    authService.Client(),
+		secret:    "s3cret",
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		headers       map[string]string
+		expectedEmail string
+		expectedRoles []string
+		expectError   bool
+	}{
+		{
+			name:          "X-API-Key Header",
+			method:        http.MethodGet,
+			headers:       map[string]string{"X-API-Key": "geh_valid"},
+			expectedEmail: "board@example.com",
+			expectedRoles: []string{"organizer"},
+		},
+		{
+			name:          "Bearer Prefix",
+			method:        http.MethodGet,
+			headers:       map[string]string{"Authorization": "Bearer geh_valid"},
+			expectedEmail: "board@example.com",
+			expectedRoles: []string{"organizer"},
+		},
+		{
+			name:        "Missing Write Scope",
+			method:      http.MethodPost,
+			headers:     map[string]string{"X-API-Key": "geh_valid"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown Key",
+			method:      http.MethodGet,
+			headers:     map[string]string{"X-API-Key": "geh_unknown"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/events/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			email, isAdmin, isActivated, err := rte.extractTokenData(req)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedEmail, email)
+			assert.False(t, isAdmin)
+			assert.True(t, isActivated)
+
+			roles, err := rte.extractRoles(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRoles, roles)
+		})
+	}
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		rte := &realTokenExtractor{apiKeyURL: authService.URL, client: authService.Client(), secret: "guess"}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/events/", nil)
+		req.Header.Set("X-API-Key", "geh_valid")
+
+		_, _, _, err := rte.extractTokenData(req)
+		assert.Error(t, err)
+	})
+}
+
+func TestExtractIdentityVerifiesOnce(t *testing.T) {
+	calls := 0
+
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		app := &application{}
+		app.writeJSON(w, http.StatusOK, envelope{
+			"api_key": envelope{"scopes": []string{"events:read"}},
+			"user":    envelope{"email": "board@example.com", "is_admin": false, "activated": true},
+			"roles":   []string{"organizer"},
+		}, nil)
+	}))
+	defer authService.Close()
+
+	rte := &realTokenExtractor{
+		apiKeyURL: authService.URL,
+		client:    authService.Client(),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/events/", nil)
+	req.Header.Set("X-API-Key", "geh_valid")
+
+	email, roles, err := rte.extractIdentity(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "board@example.com", email)
+	assert.Equal(t, []string{"organizer"}, roles)
+	assert.Equal(t, 1, calls)
+}
+
+func TestExtractTokenDataWithIntrospection(t *testing.T) {
+	calls := 0
+
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.PostFormValue("token") {
+		case "valid.jwt":
+			fmt.Fprint(w, `{"active": true, "token_type": "access_token", "sub": "7", "email": "ada@example.com", "roles": ["attendee"], "is_admin": true, "activated": true}`)
+		case "geh_reader":
+			fmt.Fprint(w, `{"active": true, "token_type": "api_key", "sub": "8", "email": "board@example.com", "roles": ["organizer"], "activated": true, "scope": "events:read"}`)
+		default:
+			fmt.Fprint(w, `{"active": false}`)
+		}
+	}))
+	defer authService.Close()
+
+	tests := []struct {
+		name          string
+		method        string
+		headers       map[string]string
+		expectedEmail string
+		expectedRoles []string
+		expectError   bool
+	}{
+		{
+			name:          "Active Token",
+			method:        http.MethodGet,
+			headers:       map[string]string{"Authorization": "Bearer valid.jwt"},
+			expectedEmail: "ada@example.com",
+			expectedRoles: []string{"attendee"},
+		},
+		{
+			name:        "Inactive Token",
+			method:      http.MethodGet,
+			headers:     map[string]string{"Authorization": "Bearer revoked.jwt"},
+			expectError: true,
+		},
+		{
+			name:          "API Key Within Scope",
+			method:        http.MethodGet,
+			headers:       map[string]string{"X-API-Key": "geh_reader"},
+			expectedEmail: "board@example.com",
+			expectedRoles: []string{"organizer"},
+		},
+		{
+			name:        "API Key Outside Scope",
+			method:      http.MethodDelete,
+			headers:     map[string]string{"X-API-Key": "geh_reader"},
+			expectError: true,
+		},
+		{
+			name:        "Missing Credentials",
+			method:      http.MethodGet,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rte := &realTokenExtractor{introspector: newIntrospector(authService.URL, "s3cret", time.Minute)}
+
+			req := httptest.NewRequest(tt.method, "/v1/events/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			email, _, isActivated, err := rte.extractTokenData(req)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedEmail, email)
+			assert.True(t, isActivated)
+
+			roles, err := rte.extractRoles(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRoles, roles)
+		})
+	}
+
+	t.Run("Results Are Cached", func(t *testing.T) {
+		rte := &realTokenExtractor{introspector: newIntrospector(authService.URL, "s3cret", time.Minute)}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/events/", nil)
+		req.Header.Set("Authorization", "Bearer valid.jwt")
+
+		before := calls
+		for i := 0; i < 3; i++ {
+			_, _, _, err := rte.extractTokenData(req)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 1, calls-before)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		rte := &realTokenExtractor{introspector: newIntrospector(authService.URL, "guess", time.Minute)}
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/events/", nil)
+		req.Header.Set("Authorization", "Bearer valid.jwt")
+
+		_, _, _, err := rte.extractTokenData(req)
+		assert.Error(t, err)
+	})
+}
+
+// testDatabase connects to the MongoDB server at MONGO_TEST_URL and returns a
+// database of its own, dropped when the test ends. Tests that need it are
+// skipped when the variable is unset or the server cannot be reached.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	url := os.Getenv("MONGO_TEST_URL")
+	if url == "" {
+		t.Skip("MONGO_TEST_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	if err != nil {
+		t.Skipf("connecting to MongoDB: %v", err)
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		client.Disconnect(context.Background())
+		t.Skipf("connecting to MongoDB: %v", err)
+	}
+
+	db := client.Database("events_test_" + primitive.NewObjectID().Hex())
+
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	return db
+}
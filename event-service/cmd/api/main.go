@@ -27,9 +27,6 @@ const (
 type config struct {
 	port string
 	env  string
-	jwt  struct {
-		secret string
-	}
 }
 
 type application struct {
@@ -44,7 +41,6 @@ func main() {
 	var cfg config
 	cfg.port = webPort
 	cfg.env = webEnv
-	cfg.jwt.secret = os.Getenv("JWT_SECRET")
 
 	// Connect to the MongoDB database
 	mongoClient, err := connectToMongo()
@@ -56,6 +52,21 @@ func main() {
 		log.Panic("could not connect to database")
 	}
 
+	err = data.CreateIndexes(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = data.LowercaseOrganizerEmails(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = data.RecountApplications(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Connect to RabbitMQ
 	rabbitConn, err := connectToRabbit()
 	if err != nil {
@@ -67,6 +78,10 @@ func main() {
 	// Create a logger
 	logger := log.New(os.Stdout, "", log.Ldate|log.LUTC)
 
+	if os.Getenv("TOKEN_INTROSPECTION_SECRET") == "" {
+		logger.Println("TOKEN_INTROSPECTION_SECRET is not set, API keys will be rejected")
+	}
+
 	// Initialize the application with models and configuration
 	app := &application{
 		config: cfg,
@@ -74,7 +89,13 @@ func main() {
 		models: data.NewModels(db),
 		Rabbit: rabbitConn,
 		tokenExtractor: &realTokenExtractor{
-			jwtSecret: cfg.jwt.secret,
+			keys:        newJWKSCache("http://authentication-service/.well-known/jwks.json"),
+			denylistURL: "http://authentication-service/v1/tokens/denylist",
+			apiKeyURL:   "http://authentication-service/v1/api-keys/verification",
+			client:      &http.Client{Timeout: 5 * time.Second},
+			secret:      os.Getenv("TOKEN_INTROSPECTION_SECRET"),
+
+			introspector: introspectorFromEnv("http://authentication-service/v1/tokens/introspect"),
 		},
 	}
 
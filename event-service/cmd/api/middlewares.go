@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 
@@ -31,7 +36,7 @@ func cors(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == http.MethodOptions {
@@ -40,4 +45,80 @@ func cors(next http.Handler) http.Handler {
 		}
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// requireRole only lets the request through when the caller's token carries at
+// least one of the given roles.
+func (app *application) requireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userRoles, err := app.tokenExtractor.extractRoles(r)
+		if err != nil {
+			app.writeJSON(w, http.StatusUnauthorized, envelope{"error": "Invalid token"}, nil)
+			return
+		}
+
+		for _, role := range roles {
+			if app.Contains(userRoles, role) {
+				next(w, r)
+				return
+			}
+		}
+
+		app.notPermittedResponse(w, r)
+	}
+}
+
+// requireEventOrganizer lets admins and the event's listed organizers through.
+func (app *application) requireEventOrganizer(next http.HandlerFunc) http.HandlerFunc {
+	return app.requireEventMember(next, (*data.Event).IsOrganizer)
+}
+
+// requireEventUsher lets admins and the ushers assigned to the event through.
+func (app *application) requireEventUsher(next http.HandlerFunc) http.HandlerFunc {
+	return app.requireEventMember(next, func(event *data.Event, email string) bool {
+		for _, usher := range event.Ushers {
+			if strings.EqualFold(usher, email) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (app *application) requireEventMember(next http.HandlerFunc, isMember func(event *data.Event, email string) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, roles, err := app.tokenExtractor.extractIdentity(r)
+		if err != nil {
+			app.writeJSON(w, http.StatusUnauthorized, envelope{"error": "Invalid token"}, nil)
+			return
+		}
+
+		if app.Contains(roles, data.RoleAdmin) {
+			next(w, r)
+			return
+		}
+
+		id, err := primitive.ObjectIDFromHex(r.PathValue("id"))
+		if err != nil {
+			app.writeJSON(w, http.StatusBadRequest, envelope{"error": "Invalid ID format"}, nil)
+			return
+		}
+
+		event, err := app.models.Event.GetEventByID(id)
+		if err != nil {
+			if errors.Is(err, data.ErrNoRecords) {
+				app.writeJSON(w, http.StatusNotFound, envelope{"error": "Event not found"}, nil)
+				return
+			}
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !isMember(event, email) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequireEventOrganizer(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request) {
+		app.writeJSON(w, http.StatusOK, envelope{"message": "Success"}, nil)
+	}
+
+	tests := []struct {
+		name           string
+		eventId        string
+		expectedStatus int
+		expectedBody   string
+		setupMock      func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor)
+	}{
+		{
+			name:           "Invalid Token",
+			eventId:        primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid token"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("", false, false, errors.New("Invalid token"))
+			},
+		},
+		{
+			name:           "Admin Is Allowed",
+			eventId:        primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Success"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("admin@example.com", true, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{"admin"}, nil)
+			},
+		},
+		{
+			name:           "Listed Organizer Is Allowed",
+			eventId:        primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Success"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("John.Doe@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{"organizer"}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{
+					Organizers: []data.Organizer{{Name: "John Doe", Email: "john.doe@example.com"}},
+				}, nil)
+			},
+		},
+		{
+			name:           "Other Organizer Is Forbidden",
+			eventId:        primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"your user account doesn't have the necessary permissions to access this resource"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("jane@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{"organizer"}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{
+					Organizers: []data.Organizer{{Name: "John Doe", Email: "john.doe@example.com"}},
+				}, nil)
+			},
+		},
+		{
+			name:           "Event Not Found",
+			eventId:        primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"Event not found"}`,
+			setupMock: func(mockEventModel *MockEventModel, mockTokenExtractor *MockTokenExtractor) {
+				mockTokenExtractor.On("extractTokenData", mock.Anything).Return("jane@example.com", false, true, nil)
+				mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{"attendee"}, nil)
+				mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{}, data.ErrNoRecords)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventModel := new(MockEventModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			app.models = data.Models{
+				EventApps: new(MockEventAppModel),
+				Event:     mockEventModel,
+			}
+			app.tokenExtractor = mockTokenExtractor
+
+			tt.setupMock(mockEventModel, mockTokenExtractor)
+
+			req := httptest.NewRequest(http.MethodPut, "/v1/events/{id}", nil)
+			req.SetPathValue("id", tt.eventId)
+
+			rr := httptest.NewRecorder()
+
+			handler := app.requireEventOrganizer(next)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+
+			mockEventModel.AssertExpectations(t)
+			mockTokenExtractor.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRequireEventUsher(t *testing.T) {
+	app := &application{
+		Logger: log.New(io.Discard, "", 0),
+		config: config{port: "80", env: "development"},
+	}
+
+	next := func(w http.ResponseWriter, r *http.Request) {
+		app.writeJSON(w, http.StatusOK, envelope{"message": "Success"}, nil)
+	}
+
+	tests := []struct {
+		name           string
+		email          string
+		expectedStatus int
+	}{
+		{name: "Assigned Usher Is Allowed", email: "alice@example.com", expectedStatus: http.StatusOK},
+		{name: "Unassigned User Is Forbidden", email: "mallory@example.com", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockEventModel := new(MockEventModel)
+			mockTokenExtractor := new(MockTokenExtractor)
+
+			app.models = data.Models{
+				EventApps: new(MockEventAppModel),
+				Event:     mockEventModel,
+			}
+			app.tokenExtractor = mockTokenExtractor
+
+			mockTokenExtractor.On("extractTokenData", mock.Anything).Return(tt.email, false, true, nil)
+			mockTokenExtractor.On("extractRoles", mock.Anything).Return([]string{"usher"}, nil)
+			mockEventModel.On("GetEventByID", mock.AnythingOfType("primitive.ObjectID")).Return(&data.Event{
+				Ushers: []string{"alice@example.com", "bob@example.com"},
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/events/{id}/checkin", nil)
+			req.SetPathValue("id", primitive.NewObjectID().Hex())
+
+			rr := httptest.NewRecorder()
+
+			handler := app.requireEventUsher(next)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+
+			mockEventModel.AssertExpectations(t)
+			mockTokenExtractor.AssertExpectations(t)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/MohamedHossam2004/Event-Planner/event-service/internal/data"
 )
 
 func (app *application) routes() http.Handler {
@@ -9,14 +11,24 @@ func (app *application) routes() http.Handler {
 
 
 	mux.HandleFunc("GET /v1/events/", app.getAllEventsHandler)                       // GET /events
+	mux.HandleFunc("GET /v1/events/search", app.searchEventsHandler)                 // GET /events/search
 	mux.HandleFunc("GET /v1/events/{id}", app.getEventByIDHandler)                   // GET /events/{id}
-	mux.HandleFunc("POST /v1/events", app.createEventHandler)                       // POST /events
-	mux.HandleFunc("PUT /v1/events/{id}", app.updateEventHandler)                    // PUT /events/{id}
-	mux.HandleFunc("DELETE /v1/events/{id}", app.deleteEventHandler)                 // DELETE /events/{id}
-	mux.HandleFunc("POST /v1/events/{id}/apply", app.applyToEventHandler)            // POST /events/{id}/apply
-	mux.HandleFunc("DELETE /v1/events/{id}/unapply", app.removeUserEventApplication) // DELETE /events/{id}/unapply
+	mux.HandleFunc("POST /v1/events", app.requireRole(app.createEventHandler, data.RoleAdmin, data.RoleOrganizer)) // POST /events
+	mux.HandleFunc("PUT /v1/events/{id}", app.requireEventOrganizer(app.updateEventHandler))                       // PUT /events/{id}
+	mux.HandleFunc("DELETE /v1/events/{id}", app.requireEventOrganizer(app.deleteEventHandler))                    // DELETE /events/{id}
+	mux.HandleFunc("POST /v1/events/{id}/apply", app.applyToEventHandler)                                          // POST /events/{id}/apply
+	mux.HandleFunc("DELETE /v1/events/{id}/unapply", app.removeUserEventApplication)                               // DELETE /events/{id}/unapply
+	mux.HandleFunc("POST /v1/events/{id}/checkin", app.requireEventUsher(app.checkInAttendeeHandler))              // POST /events/{id}/checkin
+	mux.HandleFunc("GET /v1/events/{id}/waitlist", app.waitlistPositionHandler)                                    // GET /events/{id}/waitlist
 	mux.HandleFunc("GET /v1/events/user", app.viewUnsubscribedEventsHandler)             //GET /events/user
 
+	mux.HandleFunc("POST /v1/events/{id}/submit", app.requireEventOrganizer(app.changeEventStatusHandler(data.StatusPendingReview)))   // POST /events/{id}/submit
+	mux.HandleFunc("POST /v1/events/{id}/approve", app.requireRole(app.changeEventStatusHandler(data.StatusApproved), data.RoleAdmin)) // POST /events/{id}/approve
+	mux.HandleFunc("POST /v1/events/{id}/reject", app.requireRole(app.changeEventStatusHandler(data.StatusRejected), data.RoleAdmin))  // POST /events/{id}/reject
+	mux.HandleFunc("POST /v1/events/{id}/publish", app.requireEventOrganizer(app.changeEventStatusHandler(data.StatusPublished)))      // POST /events/{id}/publish
+	mux.HandleFunc("POST /v1/events/{id}/cancel", app.requireEventOrganizer(app.changeEventStatusHandler(data.StatusCancelled)))       // POST /events/{id}/cancel
+	mux.HandleFunc("POST /v1/events/{id}/complete", app.requireEventOrganizer(app.changeEventStatusHandler(data.StatusCompleted)))     // POST /events/{id}/complete
+
 	mux.HandleFunc("GET /v1/eventApps/", app.getAllEventAppsHandler)       // GET /eventApps
 	mux.HandleFunc("GET /v1/eventApps/{id}", app.getEventAppByIDHandler)   // GET /eventApps/{id}
 	mux.HandleFunc("POST /v1/eventApps", app.createEventAppHandler)       // POST /eventApps
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +17,17 @@ var (
 	ErrEventEnded     = errors.New("Event is finished")
 	ErrAlreadyApplied = errors.New("User has already applied for this event")
 	ErrNotApplied     = errors.New("User didn't apply to event")
+	ErrWaitlisted     = errors.New("User is already on the event's waitlist")
+
+	// ErrDuplicateEventApp is returned when an event already has its
+	// applications document. There is one per event, which is what Apply and
+	// Unapply check the event's capacity against.
+	ErrDuplicateEventApp = errors.New("event already has applications")
+
+	// ErrCountNotUpdated is returned alongside a successful Apply or Unapply
+	// when only the event's number_of_applications could not be updated.
+	// RecountApplications puts the counter right again.
+	ErrCountNotUpdated = errors.New("number of applications was not updated")
 )
 
 type EventAppModelInterface interface {
@@ -23,15 +36,29 @@ type EventAppModelInterface interface {
 	UpdateEventApp(ctx context.Context, id primitive.ObjectID, update bson.M) error
 	DeleteEventApp(ctx context.Context, id primitive.ObjectID) error
 	ListEventApps(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*EventApps, error)
-	AddAttendeeToEvent(name string, eventId primitive.ObjectID) error
-	RemoveAttendeeFromEvent(name string, eventId primitive.ObjectID) error
+	Apply(email string, event *Event) (*Application, error)
+	Unapply(email string, event *Event) (*Cancellation, error)
+	CheckInAttendee(email string, eventId primitive.ObjectID) error
 	GetEventsByUserEmail(email string) ([]*Event, error)
 }
 
 type EventApps struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	EventID  primitive.ObjectID `bson:"event_id" json:"event_id" validate:"required"`
-	Attendee []string           `bson:"attendee" json:"attendee" validate:"required"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EventID   primitive.ObjectID `bson:"event_id" json:"event_id" validate:"required"`
+	Attendee  []string           `bson:"attendee" json:"attendee" validate:"required"`
+	CheckedIn []string           `bson:"checked_in" json:"checked_in"`
+	Waitlist  []string           `bson:"waitlist" json:"waitlist"`
+}
+
+// WaitlistPosition returns where email is on the waitlist, counting from 1, or
+// 0 when it is not on it.
+func (e *EventApps) WaitlistPosition(email string) int {
+	for i, waiting := range e.Waitlist {
+		if waiting == email {
+			return i + 1
+		}
+	}
+	return 0
 }
 
 type EventAppModel struct {
@@ -61,6 +88,9 @@ func (s *EventAppModel) CreateEventApp(ctx context.Context, eventApp *EventApps)
 	// Insert the event application
 	_, err = s.collection.InsertOne(ctx, eventApp)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDuplicateEventApp
+		}
 		return err
 	}
 
@@ -112,33 +142,237 @@ func (s *EventAppModel) ListEventApps(ctx context.Context, filter bson.M, opts *
 	return eventApps, nil
 }
 
-func (e *EventAppModel) AddAttendeeToEvent(name string, eventId primitive.ObjectID) error {
-	_, err := e.collection.UpdateOne(context.Background(), bson.M{"event_id": eventId}, bson.M{"$push": bson.M{"attendee": name}})
+// Application is the outcome of applying to an event: a place on it, or a
+// position on its waitlist when it is full.
+type Application struct {
+	Attending        bool
+	WaitlistPosition int
+}
+
+// Cancellation is the outcome of withdrawing from an event. Promoted is the
+// user who took over the place, if any.
+type Cancellation struct {
+	WasAttending bool
+	Promoted     string
+}
+
+// hasRoom is true when the attendee list, as of the current pipeline stage,
+// is below capacity. A capacity of zero means the event has no limit.
+func hasRoom(capacity int) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"$lte": bson.A{capacity, 0}},
+		bson.M{"$lt": bson.A{bson.M{"$size": "$attendee"}, capacity}},
+	}}
+}
+
+// withDefaultLists is a pipeline stage that turns missing attendee and
+// waitlist fields into empty arrays, so later stages can work on them.
+var withDefaultLists = bson.D{{Key: "$set", Value: bson.M{
+	"attendee": bson.M{"$ifNull": bson.A{"$attendee", bson.A{}}},
+	"waitlist": bson.M{"$ifNull": bson.A{"$waitlist", bson.A{}}},
+}}}
+
+// Apply gives the user a place on the event, or puts them at the back of its
+// waitlist when it is full. Checking that the user has not applied yet,
+// checking capacity and adding them is a single update of the event's
+// applications, so concurrent applications can neither apply a user twice nor
+// overfill the event.
+func (e *EventAppModel) Apply(email string, event *Event) (*Application, error) {
+	if !event.Date.After(time.Now()) {
+		return nil, ErrEventEnded
+	}
+
+	filter := bson.M{
+		"event_id": event.ID,
+		"attendee": bson.M{"$ne": email},
+		"waitlist": bson.M{"$ne": email},
+	}
+
+	room := hasRoom(event.MaxCapacity)
+
+	update := mongo.Pipeline{
+		withDefaultLists,
+		{{Key: "$set", Value: bson.M{
+			"attendee": bson.M{"$cond": bson.A{room, bson.M{"$concatArrays": bson.A{"$attendee", bson.A{email}}}, "$attendee"}},
+			"waitlist": bson.M{"$cond": bson.A{room, "$waitlist", bson.M{"$concatArrays": bson.A{"$waitlist", bson.A{email}}}}},
+		}}},
+	}
+
+	var eventApp EventApps
+	err := e.collection.FindOneAndUpdate(
+		context.Background(),
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&eventApp)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return e.alreadyApplied(email, event.ID)
+		}
+		return nil, err
+	}
+
+	if position := eventApp.WaitlistPosition(email); position > 0 {
+		return &Application{WaitlistPosition: position}, nil
+	}
+
+	return &Application{Attending: true}, e.countApplications(event.ID, 1)
+}
+
+// alreadyApplied works out why Apply matched no applications: there are none
+// for the event, or the user is already attending or waiting.
+func (e *EventAppModel) alreadyApplied(email string, eventId primitive.ObjectID) (*Application, error) {
+	eventApp, err := e.GetEventApp(context.Background(), eventId)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if position := eventApp.WaitlistPosition(email); position > 0 {
+		return &Application{WaitlistPosition: position}, ErrWaitlisted
+	}
+
+	return &Application{Attending: true}, ErrAlreadyApplied
+}
+
+// Unapply takes the user off the event's attendee list or waitlist. When that
+// frees a place, the first user on the waitlist is moved onto the attendee
+// list in the same update, so two places opening up at once promote two
+// different users.
+func (e *EventAppModel) Unapply(email string, event *Event) (*Cancellation, error) {
+	if !event.Date.After(time.Now()) {
+		return nil, ErrEventEnded
+	}
+
+	filter := bson.M{
+		"event_id": event.ID,
+		"$or":      bson.A{bson.M{"attendee": email}, bson.M{"waitlist": email}},
+	}
+
+	promote := bson.M{"$and": bson.A{
+		bson.M{"$gt": bson.A{bson.M{"$size": "$waitlist"}, 0}},
+		hasRoom(event.MaxCapacity),
+	}}
+
+	update := mongo.Pipeline{
+		withDefaultLists,
+		{{Key: "$set", Value: bson.M{
+			"attendee": bson.M{"$filter": bson.M{"input": "$attendee", "cond": bson.M{"$ne": bson.A{"$$this", email}}}},
+			"waitlist": bson.M{"$filter": bson.M{"input": "$waitlist", "cond": bson.M{"$ne": bson.A{"$$this", email}}}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"attendee": bson.M{"$cond": bson.A{promote, bson.M{"$concatArrays": bson.A{"$attendee", bson.M{"$slice": bson.A{"$waitlist", 1}}}}, "$attendee"}},
+			"waitlist": bson.M{"$cond": bson.A{promote, bson.M{"$slice": bson.A{"$waitlist", 1, bson.M{"$max": bson.A{bson.M{"$size": "$waitlist"}, 1}}}}, "$waitlist"}},
+		}}},
 	}
-	update := bson.M{"$inc": bson.M{"number_of_applications": -1}}
-	_, err = e.eventService.collection.UpdateOne(
+
+	var before EventApps
+	err := e.collection.FindOneAndUpdate(
 		context.Background(),
-		bson.M{"_id": eventId},
+		filter,
 		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			_, err = e.GetEventApp(context.Background(), event.ID)
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrNotApplied
+		}
+		return nil, err
+	}
+
+	// Replay the update on the document as it was to learn what it did.
+	cancellation := &Cancellation{WasAttending: slices.Contains(before.Attendee, email)}
+
+	attending := slices.DeleteFunc(slices.Clone(before.Attendee), func(s string) bool { return s == email })
+	waiting := slices.DeleteFunc(slices.Clone(before.Waitlist), func(s string) bool { return s == email })
+
+	if len(waiting) > 0 && (event.MaxCapacity <= 0 || len(attending) < event.MaxCapacity) {
+		cancellation.Promoted = waiting[0]
+	}
+
+	if cancellation.WasAttending && cancellation.Promoted == "" {
+		err = e.countApplications(event.ID, -1)
+	} else if !cancellation.WasAttending && cancellation.Promoted != "" {
+		err = e.countApplications(event.ID, 1)
+	}
+
+	return cancellation, err
+}
+
+// countApplications keeps the event's number_of_applications in step with
+// its attendee list. Apply and Unapply only call it once their own update has
+// gone through, so each place is counted exactly once. By then the user's
+// place is settled, so a failure here is reported as ErrCountNotUpdated
+// rather than as a failed application.
+func (e *EventAppModel) countApplications(eventId primitive.ObjectID, delta int) error {
+	_, err := e.eventService.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": eventId},
+		bson.M{"$inc": bson.M{"number_of_applications": delta}},
 	)
-	return err
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCountNotUpdated, err)
+	}
+	return nil
 }
 
-func (e *EventAppModel) RemoveAttendeeFromEvent(name string, eventId primitive.ObjectID) error {
-	_, err := e.collection.UpdateOne(context.Background(), bson.M{"event_id": eventId}, bson.M{"$pull": bson.M{"attendee": name}})
+// RecountApplications sets every event's number_of_applications to the size
+// of its attendee list. Counters could drift before applying was atomic, some
+// of them below zero, and can still miss an update that hit ErrCountNotUpdated,
+// so this runs at every start.
+func RecountApplications(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Events without any applications are not reached by the pipeline below.
+	_, err := db.Collection("events").UpdateMany(
+		ctx,
+		bson.M{"number_of_applications": bson.M{"$lt": 0}},
+		bson.M{"$set": bson.M{"number_of_applications": 0}},
+	)
 	if err != nil {
 		return err
 	}
-	update := bson.M{"$inc": bson.M{"number_of_applications": 1}}
-	_, err = e.eventService.collection.UpdateOne(
+
+	cursor, err := db.Collection("event_apps").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$project", Value: bson.M{
+			"_id":                    "$event_id",
+			"number_of_applications": bson.M{"$size": bson.M{"$ifNull": bson.A{"$attendee", bson.A{}}}},
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "events",
+			"on":             "_id",
+			"whenMatched":    "merge",
+			"whenNotMatched": "discard",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return cursor.Close(ctx)
+}
+
+// CheckInAttendee marks an attendee as present. Only users on the attendee list
+// can be checked in.
+func (e *EventAppModel) CheckInAttendee(email string, eventId primitive.ObjectID) error {
+	result, err := e.collection.UpdateOne(
 		context.Background(),
-		bson.M{"_id": eventId},
-		update,
+		bson.M{"event_id": eventId, "attendee": email},
+		bson.M{"$addToSet": bson.M{"checked_in": email}},
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if result.MatchedCount == 0 {
+		return ErrNotApplied
+	}
+
+	return nil
 }
 
 func (e *EventAppModel) GetEventsByUserEmail(email string) ([]*Event, error) {
@@ -0,0 +1,230 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestEvent stores an event of the given capacity along with its empty
+// applications.
+func newTestEvent(t *testing.T, models Models, capacity int) *Event {
+	t.Helper()
+
+	event, err := models.Event.CreateEvent(&Event{Name: "Go Workshop", MaxCapacity: capacity, Date: time.Now().Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.EventApps.CreateEventApp(context.Background(), &EventApps{EventID: event.ID, Attendee: []string{}, Waitlist: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
+
+func TestApplyAndUnapplyConcurrently(t *testing.T) {
+	const (
+		capacity = 5
+		users    = 30
+	)
+
+	models := NewModels(testDatabase(t))
+	event := newTestEvent(t, models, capacity)
+
+	emails := make([]string, users)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+
+	// Everyone applies at once, each of them twice.
+	applications := make([]*Application, 2*users)
+	errs := make([]error, 2*users)
+
+	var wg sync.WaitGroup
+	for i := range applications {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applications[i], errs[i] = models.EventApps.Apply(emails[i%users], event)
+		}()
+	}
+	wg.Wait()
+
+	attending := map[string]bool{}
+	positions := map[int]bool{}
+	repeated := 0
+
+	for i, application := range applications {
+		switch {
+		case errors.Is(errs[i], ErrAlreadyApplied), errors.Is(errs[i], ErrWaitlisted):
+			repeated++
+		case errs[i] != nil:
+			t.Fatalf("applying as %s: %v", emails[i%users], errs[i])
+		case application.Attending:
+			attending[emails[i%users]] = true
+		default:
+			assert.False(t, positions[application.WaitlistPosition], "waitlist position %d given out twice", application.WaitlistPosition)
+			positions[application.WaitlistPosition] = true
+		}
+	}
+
+	assert.Equal(t, users, repeated)
+	assert.Len(t, attending, capacity)
+	assert.Len(t, positions, users-capacity)
+
+	eventApp, err := models.EventApps.GetEventApp(context.Background(), event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, eventApp.Attendee, capacity)
+	assert.Len(t, eventApp.Waitlist, users-capacity)
+
+	stored, err := models.Event.GetEventByID(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, capacity, stored.NumberOfApplications)
+
+	// Every attendee withdraws at once. Each freed place goes to a different
+	// user, in waitlist order.
+	waitlist := eventApp.Waitlist
+	cancellations := make([]*Cancellation, capacity)
+
+	for i, email := range eventApp.Attendee {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancellations[i], errs[i] = models.EventApps.Unapply(email, event)
+		}()
+	}
+	wg.Wait()
+
+	promoted := []string{}
+	for i, cancellation := range cancellations {
+		if errs[i] != nil {
+			t.Fatalf("withdrawing: %v", errs[i])
+		}
+		assert.True(t, cancellation.WasAttending)
+		promoted = append(promoted, cancellation.Promoted)
+	}
+	assert.ElementsMatch(t, waitlist[:capacity], promoted)
+
+	eventApp, err = models.EventApps.GetEventApp(context.Background(), event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.ElementsMatch(t, waitlist[:capacity], eventApp.Attendee)
+	assert.Equal(t, waitlist[capacity:], eventApp.Waitlist)
+
+	stored, err = models.Event.GetEventByID(event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, capacity, stored.NumberOfApplications)
+
+	// Withdrawing twice is refused.
+	_, err = models.EventApps.Unapply(promoted[0], event)
+	assert.NoError(t, err)
+	_, err = models.EventApps.Unapply(promoted[0], event)
+	assert.ErrorIs(t, err, ErrNotApplied)
+}
+
+func TestRecountApplications(t *testing.T) {
+	db := testDatabase(t)
+	models := NewModels(db)
+
+	negative := newTestEvent(t, models, 10)
+	stale := newTestEvent(t, models, 10)
+
+	err := models.EventApps.UpdateEventApp(context.Background(), stale.ID, bson.M{"attendee": []string{"a@example.com", "b@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unapplied, err := models.Event.CreateEvent(&Event{Name: "Go Meetup", Date: time.Now().Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[*Event]int{negative: -3, stale: 7, unapplied: -1}
+	for event, count := range counts {
+		_, err = db.Collection("events").UpdateByID(context.Background(), event.ID, bson.M{"$set": bson.M{"number_of_applications": count}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = RecountApplications(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[*Event]int{negative: 0, stale: 2, unapplied: 0}
+	for event, count := range expected {
+		stored, err := models.Event.GetEventByID(event.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, count, stored.NumberOfApplications, event.Name)
+		assert.Equal(t, event.Name, stored.Name)
+	}
+}
+
+func TestCreateIndexesMergesDuplicateEventApps(t *testing.T) {
+	db := testDatabase(t)
+	models := NewModels(db)
+
+	event := newTestEvent(t, models, 10)
+
+	// A second document for the same event, as POST /v1/eventApps could
+	// create before event_id was unique.
+	_, err := db.Collection("event_apps").InsertOne(context.Background(), EventApps{
+		ID:        primitive.NewObjectID(),
+		EventID:   event.ID,
+		Attendee:  []string{"a@example.com", "b@example.com"},
+		CheckedIn: []string{"a@example.com"},
+		Waitlist:  []string{"c@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = models.EventApps.UpdateEventApp(context.Background(), event.ID, bson.M{
+		"attendee": []string{"b@example.com", "d@example.com"},
+		"waitlist": []string{"a@example.com", "e@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CreateIndexes(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := db.Collection("event_apps").CountDocuments(context.Background(), bson.M{"event_id": event.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), count)
+
+	apps, err := models.EventApps.GetEventApp(context.Background(), event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"b@example.com", "d@example.com", "a@example.com"}, apps.Attendee)
+	assert.Equal(t, []string{"a@example.com"}, apps.CheckedIn)
+	assert.Equal(t, []string{"e@example.com", "c@example.com"}, apps.Waitlist)
+
+	err = models.EventApps.CreateEventApp(context.Background(), &EventApps{EventID: event.ID, Attendee: []string{}})
+	assert.ErrorIs(t, err, ErrDuplicateEventApp)
+}
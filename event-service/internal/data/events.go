@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"log"
+	"maps"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -18,9 +21,17 @@ var (
 type EventModelInterface interface {
 	CreateEvent(event *Event) (*Event, error)
 	GetEventByID(id primitive.ObjectID) (*Event, error)
-	UpdateEvent(id primitive.ObjectID, event *Event) (*Event, error)
+	UpdateEvent(id primitive.ObjectID, event *Event, editor string) (*Event, error)
 	DeleteEvent(id primitive.ObjectID) error
 	GetAllEvents() ([]Event, error)
+	ListEvents(filters EventFilters) ([]Event, Metadata, error)
+	SearchEvents(query string, filters EventFilters) ([]SearchResult, Metadata, error)
+	ChangeStatus(id primitive.ObjectID, change StatusChange) (*Event, error)
+	ChangeSeriesStatus(occurrence *Event, change StatusChange, scope, organizer string) ([]Event, error)
+	CreateSeries(event *Event, rule RRule, dates []time.Time) ([]Event, error)
+	DeleteSeries(seriesID primitive.ObjectID) error
+	UpdateSeries(occurrence *Event, event *Event, scope, editor string) ([]Event, error)
+	ExcludeOccurrence(occurrence *Event) error
 }
 
 // EventType represents the type of event
@@ -36,6 +47,13 @@ const (
 	Other      EventType = "OTHER"
 )
 
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleUsher     = "usher"
+	RoleAttendee  = "attendee"
+)
+
 type EventModel struct {
 	collection *mongo.Collection
 }
@@ -56,6 +74,9 @@ type Event struct {
 	CreatedAt            time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt            time.Time          `bson:"updated_at" json:"updated_at"`
 	Status               string             `bson:"status" json:"status"`
+	StatusReason         string             `bson:"status_reason,omitempty" json:"status_reason,omitempty"`
+	StatusHistory        []StatusChange     `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	Recurrence           *Recurrence        `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
 }
 
 // Location represents the event location details
@@ -75,7 +96,8 @@ type Organizer struct {
 	Role  string             `bson:"role" json:"role"`
 }
 
-// CreateIndexes creates the necessary indexes for the Event collection
+// CreateEventIndexes returns the indexes the Event collection needs for
+// listing and filtering events.
 func CreateEventIndexes() []mongo.IndexModel {
 	return []mongo.IndexModel{
 		{
@@ -87,7 +109,16 @@ func CreateEventIndexes() []mongo.IndexModel {
 		{
 			Keys: bson.D{
 				{Key: "name", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "organizers.name", Value: "text"},
 			},
+			Options: options.Index().
+				SetName(eventTextIndex).
+				SetWeights(bson.D{
+					{Key: "name", Value: 10},
+					{Key: "organizers.name", Value: 5},
+					{Key: "description", Value: 1},
+				}),
 		},
 		{
 			Keys: bson.D{
@@ -100,6 +131,18 @@ func CreateEventIndexes() []mongo.IndexModel {
 				{Key: "status", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "organizers.email", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "recurrence.series_id", Value: 1},
+				{Key: "recurrence.occurrence", Value: 1},
+			},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 }
 
@@ -112,10 +155,14 @@ func NewEventService(db *mongo.Database, collectionName string) *EventModel {
 
 // CreateEvent adds a new event to the database
 func (es EventModel) CreateEvent(event *Event) (*Event, error) {
+	event.lowercaseEmails()
 	event.ID = primitive.NewObjectID()
 	event.CreatedAt = time.Now()
 	event.UpdatedAt = time.Now()
-	event.Status = "PENDING" // Initial status of an event
+	event.Status = StatusDraft
+	event.StatusReason = ""
+	event.StatusHistory = nil
+	event.Recurrence = nil
 	_, err := es.collection.InsertOne(context.Background(), event)
 	if err != nil {
 		return nil, err
@@ -139,16 +186,28 @@ func (es EventModel) GetEventByID(id primitive.ObjectID) (*Event, error) {
 	return &event, nil
 }
 
-// UpdateEvent updates an existing event
-func (es EventModel) UpdateEvent(id primitive.ObjectID, event *Event) (*Event, error) {
+// UpdateEvent updates an existing event. Its status can only be changed
+// through ChangeStatus, its number of applications is kept by the event
+// applications and its place in a series by the series methods, so those
+// fields of event are ignored. An edit by an organizer, named by editor, sends
+// an approved or published event back for review in the same update; admins
+// review events themselves and pass an empty editor.
+func (es EventModel) UpdateEvent(id primitive.ObjectID, event *Event, editor string) (*Event, error) {
+	event.lowercaseEmails()
 	event.UpdatedAt = time.Now()
 	filter := bson.D{{Key: "_id", Value: id}}
 
-	update := bson.D{
-		{Key: "$set", Value: event},
+	fields, err := updatableFields(event, "recurrence")
+	if err != nil {
+		return nil, err
+	}
+
+	set := literals(fields)
+	if editor != "" {
+		maps.Copy(set, sendBackForReview(editor, event.UpdatedAt))
 	}
 
-	_, err := es.collection.UpdateOne(context.Background(), filter, update)
+	_, err = es.collection.UpdateOne(context.Background(), filter, mongo.Pipeline{{{Key: "$set", Value: set}}})
 	if err != nil {
 		return nil, err
 	}
@@ -156,6 +215,45 @@ func (es EventModel) UpdateEvent(id primitive.ObjectID, event *Event) (*Event, e
 	return es.GetEventByID(id)
 }
 
+// lowercaseEmails stores the organizers' emails in lower case, which is how
+// EventFilters.query looks them up.
+func (e *Event) lowercaseEmails() {
+	for i := range e.Organizers {
+		e.Organizers[i].Email = strings.ToLower(e.Organizers[i].Email)
+	}
+}
+
+// updatableFields returns the fields of event that an edit may set, leaving out
+// those kept by other means and any named in ignore.
+func updatableFields(event *Event, ignore ...string) (bson.M, error) {
+	doc, err := bson.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields bson.M
+	err = bson.Unmarshal(doc, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range append([]string{"_id", "status", "status_reason", "status_history", "number_of_applications"}, ignore...) {
+		delete(fields, key)
+	}
+
+	return fields, nil
+}
+
+// literals wraps each of fields in $literal, for updates made as a pipeline,
+// so that a value such as "$5 entry" is not read as an expression.
+func literals(fields bson.M) bson.M {
+	set := bson.M{}
+	for key, value := range fields {
+		set[key] = bson.M{"$literal": value}
+	}
+	return set
+}
+
 // DeleteEvent removes an event from the database
 func (es EventModel) DeleteEvent(id primitive.ObjectID) error {
 	filter := bson.D{{Key: "_id", Value: id}}
@@ -187,3 +285,36 @@ func (es EventModel) GetAllEvents() ([]Event, error) {
 	}
 	return events, nil
 }
+
+// ListEvents returns one page of the events matching filters, along with
+// paging metadata for all of them.
+func (es EventModel) ListEvents(filters EventFilters) ([]Event, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	query := filters.query()
+
+	total, err := es.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	opts := options.Find().
+		SetSort(filters.sort()).
+		SetSkip(filters.skip()).
+		SetLimit(filters.limit())
+
+	cursor, err := es.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []Event{}
+	err = cursor.All(ctx, &events)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return events, calculateMetadata(int(total), filters.Page, filters.PageSize), nil
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventTypes lists every valid EventType.
+var EventTypes = []EventType{Conference, Workshop, Meetup, Social, CareerFair, Graduation, Other}
+
+// eventSortFields maps the sort values clients may use to document fields.
+var eventSortFields = map[string]string{
+	"date":         "date",
+	"name":         "name",
+	"created_at":   "created_at",
+	"max_capacity": "max_capacity",
+}
+
+// EventFilters narrows down, orders and pages a listing of events. Empty
+// fields, zero times and a zero SeriesID do not filter.
+type EventFilters struct {
+	Type           EventType
+	Status         string
+	City           string
+	Country        string
+	OrganizerEmail string
+	SeriesID       primitive.ObjectID
+	From           time.Time
+	To             time.Time
+	Sort           string
+	Direction      string
+	Page           int
+	PageSize       int
+
+	// PublishedOnly hides events that are not published, except for those
+	// organized by Viewer when it is set.
+	PublishedOnly bool
+	Viewer        string
+}
+
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records"`
+}
+
+// ValidateEventFilters returns the problems with f keyed by query parameter,
+// or an empty map.
+func ValidateEventFilters(f EventFilters) map[string]string {
+	errors := map[string]string{}
+
+	if f.Type != "" && !slices.Contains(EventTypes, f.Type) {
+		errors["type"] = "must be one of CONFERENCE, WORKSHOP, MEETUP, SOCIAL, CAREER_FAIR, GRADUATION or OTHER"
+	}
+
+	if f.Status != "" && !slices.Contains(EventStatuses, f.Status) {
+		errors["status"] = "must be one of DRAFT, PENDING_REVIEW, APPROVED, REJECTED, PUBLISHED, CANCELLED or COMPLETED"
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		errors["to"] = "must not be before from"
+	}
+
+	if _, ok := eventSortFields[f.Sort]; !ok {
+		errors["sort"] = "must be one of date, name, created_at or max_capacity"
+	}
+
+	if f.Direction != "asc" && f.Direction != "desc" {
+		errors["direction"] = "must be asc or desc"
+	}
+
+	if f.Page <= 0 || f.Page > 10_000_000 {
+		errors["page"] = "must be between 1 and 10 million"
+	}
+
+	if f.PageSize <= 0 || f.PageSize > 100 {
+		errors["page_size"] = "must be between 1 and 100"
+	}
+
+	return errors
+}
+
+// query builds the find filter. Conditions that may clash with others on the
+// same field go in an $and list, which callers can append to.
+func (f EventFilters) query() bson.M {
+	query := bson.M{}
+
+	if f.Type != "" {
+		query["type"] = f.Type
+	}
+
+	if f.Status != "" {
+		query["status"] = f.Status
+	}
+
+	if f.City != "" {
+		query["location.city"] = f.City
+	}
+
+	if f.Country != "" {
+		query["location.country"] = f.Country
+	}
+
+	if f.OrganizerEmail != "" {
+		query["organizers.email"] = strings.ToLower(f.OrganizerEmail)
+	}
+
+	if !f.SeriesID.IsZero() {
+		query["recurrence.series_id"] = f.SeriesID
+	}
+
+	date := bson.M{}
+	if !f.From.IsZero() {
+		date["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		date["$lte"] = f.To
+	}
+	if len(date) > 0 {
+		query["date"] = date
+	}
+
+	if f.PublishedOnly {
+		visible := bson.M{"status": StatusPublished}
+		if f.Viewer != "" {
+			visible = bson.M{"$or": bson.A{visible, bson.M{"organizers.email": strings.ToLower(f.Viewer)}}}
+		}
+		query["$and"] = bson.A{visible}
+	}
+
+	return query
+}
+
+// sort orders by the chosen field and then by _id, so that events with equal
+// values do not move between pages.
+func (f EventFilters) sort() bson.D {
+	direction := 1
+	if f.Direction == "desc" {
+		direction = -1
+	}
+
+	return bson.D{
+		{Key: eventSortFields[f.Sort], Value: direction},
+		{Key: "_id", Value: direction},
+	}
+}
+
+func (f EventFilters) limit() int64 {
+	return int64(f.PageSize)
+}
+
+func (f EventFilters) skip() int64 {
+	return int64((f.Page - 1) * f.PageSize)
+}
+
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
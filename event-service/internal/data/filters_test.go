@@ -0,0 +1,36 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEventFiltersQueryLowercasesEmails(t *testing.T) {
+	filters := EventFilters{
+		OrganizerEmail: "Jane.Doe@Example.com",
+		PublishedOnly:  true,
+		Viewer:         "John.Doe@Example.com",
+	}
+
+	assert.Equal(t, bson.M{
+		"organizers.email": "jane.doe@example.com",
+		"$and": bson.A{bson.M{"$or": bson.A{
+			bson.M{"status": StatusPublished},
+			bson.M{"organizers.email": "john.doe@example.com"},
+		}}},
+	}, filters.query())
+}
+
+func TestLowercaseEmails(t *testing.T) {
+	event := Event{Organizers: []Organizer{
+		{Name: "Jane", Email: "Jane.Doe@Example.com"},
+		{Name: "John", Email: "john.doe@example.com"},
+	}}
+
+	event.lowercaseEmails()
+
+	assert.Equal(t, "jane.doe@example.com", event.Organizers[0].Email)
+	assert.Equal(t, "john.doe@example.com", event.Organizers[1].Email)
+}
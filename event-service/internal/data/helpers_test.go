@@ -0,0 +1,47 @@
+package data
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase connects to the MongoDB server at MONGO_TEST_URL and returns a
+// database of its own, dropped when the test ends. Tests that need it are
+// skipped when the variable is unset or the server cannot be reached.
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	url := os.Getenv("MONGO_TEST_URL")
+	if url == "" {
+		t.Skip("MONGO_TEST_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	if err != nil {
+		t.Skipf("connecting to MongoDB: %v", err)
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		client.Disconnect(context.Background())
+		t.Skipf("connecting to MongoDB: %v", err)
+	}
+
+	db := client.Database("events_test_" + primitive.NewObjectID().Hex())
+
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	return db
+}
@@ -1,9 +1,14 @@
 package data
 
 import (
+	"context"
+	"slices"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const dbTimeout = 3 * time.Second
@@ -24,3 +29,141 @@ func NewModels(db *mongo.Database) Models {
 		},
 	}
 }
+
+// CreateIndexes creates the indexes the models' queries rely on. Creating an
+// index that already exists is a no-op, so this is safe to run at every start.
+func CreateIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indexes := db.Collection("events").Indexes()
+
+	// A collection can have only one text index, so one made from an older
+	// definition has to go before the current one can be created.
+	specs, err := indexes.ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		fts, ok := spec.KeysDocument.Lookup("_fts").StringValueOK()
+		if ok && fts == "text" && spec.Name != eventTextIndex {
+			_, err = indexes.DropOne(ctx, spec.Name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = indexes.CreateMany(ctx, CreateEventIndexes())
+	if err != nil {
+		return err
+	}
+
+	// Each event has a single applications document, which Apply and Unapply
+	// check its capacity against.
+	apps := db.Collection("event_apps")
+
+	err = mergeDuplicateEventApps(ctx, apps)
+	if err != nil {
+		return err
+	}
+
+	_, err = apps.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "event_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// mergeDuplicateEventApps folds the applications documents of events that
+// have more than one, as could be created before event_id was unique, into the
+// oldest of them. Users keep their place in the order they were first listed
+// in, and attendees are taken off the waitlist.
+func mergeDuplicateEventApps(ctx context.Context, apps *mongo.Collection) error {
+	cursor, err := apps.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$event_id",
+			"apps":  bson.M{"$push": "$$ROOT"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var duplicates []struct {
+		Apps []EventApps `bson:"apps"`
+	}
+	err = cursor.All(ctx, &duplicates)
+	if err != nil {
+		return err
+	}
+
+	for _, duplicate := range duplicates {
+		kept := duplicate.Apps[0]
+		var extra []primitive.ObjectID
+
+		for _, app := range duplicate.Apps[1:] {
+			kept.Attendee = appendMissing(kept.Attendee, app.Attendee)
+			kept.CheckedIn = appendMissing(kept.CheckedIn, app.CheckedIn)
+			kept.Waitlist = appendMissing(kept.Waitlist, app.Waitlist)
+			extra = append(extra, app.ID)
+		}
+
+		kept.Waitlist = slices.DeleteFunc(kept.Waitlist, func(email string) bool {
+			return slices.Contains(kept.Attendee, email)
+		})
+
+		_, err = apps.UpdateByID(ctx, kept.ID, bson.M{"$set": bson.M{
+			"attendee":   kept.Attendee,
+			"checked_in": kept.CheckedIn,
+			"waitlist":   kept.Waitlist,
+		}})
+		if err != nil {
+			return err
+		}
+
+		_, err = apps.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": extra}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendMissing appends the emails not in list yet.
+func appendMissing(list, emails []string) []string {
+	for _, email := range emails {
+		if !slices.Contains(list, email) {
+			list = append(list, email)
+		}
+	}
+	return list
+}
+
+// LowercaseOrganizerEmails lower-cases the organizer emails of events stored
+// before they were kept in lower case, so that the queries matching them in
+// lower case find those events too.
+func LowercaseOrganizerEmails(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("events").UpdateMany(
+		ctx,
+		bson.M{"organizers.email": bson.M{"$regex": "[A-Z]"}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"organizers": bson.M{"$map": bson.M{
+				"input": "$organizers",
+				"in": bson.M{"$mergeObjects": bson.A{
+					"$$this",
+					bson.M{"email": bson.M{"$toLower": "$$this.email"}},
+				}},
+			}},
+		}}}},
+	)
+	return err
+}
@@ -0,0 +1,690 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxOccurrences caps how many events a single series can create.
+const maxOccurrences = 365
+
+var (
+	ErrNotRecurring = errors.New("event is not part of a series")
+	ErrNotOrganizer = errors.New("not an organizer of every occurrence")
+)
+
+// finishedStatuses are the statuses of occurrences that edits of a series
+// leave alone: what has taken place or been called off stays as it was.
+var finishedStatuses = bson.A{StatusCompleted, StatusCancelled}
+
+// A series is edited one occurrence at a time, from an occurrence onwards or
+// as a whole.
+const (
+	ScopeThis      = "this"
+	ScopeFollowing = "following"
+	ScopeAll       = "all"
+)
+
+// Recurrence ties an event to the series it is an occurrence of. Every
+// occurrence is an event of its own, with its own applications; together they
+// carry the series' iCalendar RRULE, DTSTART and EXDATE list, and Occurrence
+// is the date the rule gave this one (its RECURRENCE-ID), which stays put when
+// the occurrence alone is moved.
+type Recurrence struct {
+	SeriesID   primitive.ObjectID `bson:"series_id" json:"series_id"`
+	RRule      string             `bson:"rrule" json:"rrule"`
+	Start      time.Time          `bson:"start" json:"start"`
+	Occurrence time.Time          `bson:"occurrence" json:"occurrence"`
+	ExDates    []time.Time        `bson:"exdates,omitempty" json:"exdates,omitempty"`
+}
+
+// RRule is a parsed iCalendar recurrence rule. It supports FREQ (DAILY,
+// WEEKLY, MONTHLY or YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and
+// WKST, and has to end through COUNT or UNTIL.
+type RRule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	weekStart  time.Weekday
+}
+
+// weekdayNum is a BYDAY entry such as TU, 1MO or -1FR. An n of zero means
+// every such day in the period.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+const untilLayout = "20060102T150405Z"
+
+// ParseRRule parses a rule such as FREQ=WEEKLY;COUNT=10;BYDAY=TU. The errors
+// it returns are meant for the user.
+func ParseRRule(s string) (RRule, error) {
+	rule := RRule{interval: 1, weekStart: time.Monday}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		key, value, _ := strings.Cut(part, "=")
+		key = strings.ToUpper(key)
+
+		if seen[key] {
+			return RRule{}, fmt.Errorf("%s is set more than once", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.freq = strings.ToUpper(value)
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, rule.freq) {
+				err = errors.New("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err != nil || rule.interval <= 0 {
+				err = errors.New("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+			if err != nil || rule.count <= 0 || rule.count > maxOccurrences {
+				err = fmt.Errorf("COUNT must be between 1 and %d", maxOccurrences)
+			}
+		case "UNTIL":
+			rule.until, err = parseUntil(value)
+		case "BYDAY":
+			rule.byDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			day, ok := weekdays[strings.ToUpper(value)]
+			if !ok {
+				err = errors.New("WKST must be a day such as MO or SU")
+			}
+			rule.weekStart = day
+		default:
+			err = fmt.Errorf("%s is not supported", key)
+		}
+		if err != nil {
+			return RRule{}, err
+		}
+	}
+
+	switch {
+	case rule.freq == "":
+		return RRule{}, errors.New("FREQ must be set")
+	case rule.count > 0 && !rule.until.IsZero():
+		return RRule{}, errors.New("COUNT and UNTIL cannot both be set")
+	case rule.count == 0 && rule.until.IsZero():
+		return RRule{}, errors.New("must set COUNT or UNTIL")
+	case len(rule.byMonthDay) > 0 && rule.freq != "MONTHLY":
+		return RRule{}, errors.New("BYMONTHDAY can only be used with FREQ=MONTHLY")
+	case len(rule.byDay) > 0 && rule.freq == "YEARLY":
+		return RRule{}, errors.New("BYDAY cannot be used with FREQ=YEARLY")
+	}
+
+	for _, wn := range rule.byDay {
+		if wn.n != 0 && rule.freq != "MONTHLY" {
+			return RRule{}, errors.New("BYDAY can only number days, as in 1MO, with FREQ=MONTHLY")
+		}
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse(untilLayout, value); err == nil {
+		return t, nil
+	}
+
+	// A bare date includes the whole of that day.
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+
+	return time.Time{}, errors.New("UNTIL must be a date such as 20250131 or 20250131T090000Z")
+}
+
+func parseByDay(value string) ([]weekdayNum, error) {
+	var days []weekdayNum
+
+	for _, s := range strings.Split(strings.ToUpper(value), ",") {
+		if len(s) < 2 {
+			return nil, errors.New("BYDAY must list days such as MO,TU or, monthly, 1MO and -1FR")
+		}
+
+		day, ok := weekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, errors.New("BYDAY must list days such as MO,TU or, monthly, 1MO and -1FR")
+		}
+
+		wn := weekdayNum{day: day}
+		if prefix := s[:len(s)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, errors.New("BYDAY can only number days from -5 to 5, as in 1MO or -1FR")
+			}
+			wn.n = n
+		}
+
+		days = append(days, wn)
+	}
+
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+
+	for _, s := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(s)
+		if err != nil || day == 0 || day < -31 || day > 31 {
+			return nil, errors.New("BYMONTHDAY must list days of the month from 1 to 31, or -31 to -1")
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+// String formats the rule in a canonical form, which is how it is stored.
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.freq}
+
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+	if r.count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.count))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "UNTIL="+r.until.UTC().Format(untilLayout))
+	}
+
+	if len(r.byDay) > 0 {
+		days := make([]string, len(r.byDay))
+		for i, wn := range r.byDay {
+			days[i] = weekdayCodes[wn.day]
+			if wn.n != 0 {
+				days[i] = strconv.Itoa(wn.n) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if len(r.byMonthDay) > 0 {
+		days := make([]string, len(r.byMonthDay))
+		for i, day := range r.byMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	if r.weekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayCodes[r.weekStart])
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Occurrences lists the dates the rule gives for a series starting at start,
+// leaving out exdates. Like in iCalendar, excluded dates still count towards
+// COUNT.
+func (r RRule) Occurrences(start time.Time, exdates []time.Time) ([]time.Time, error) {
+	var dates []time.Time
+	tooMany := false
+
+	r.each(start, func(t time.Time) bool {
+		if slices.ContainsFunc(exdates, t.Equal) {
+			return true
+		}
+		if len(dates) == maxOccurrences {
+			tooMany = true
+			return false
+		}
+		dates = append(dates, t)
+		return true
+	})
+
+	switch {
+	case tooMany:
+		return nil, fmt.Errorf("must not have more than %d occurrences", maxOccurrences)
+	case len(dates) == 0:
+		return nil, errors.New("must have at least one occurrence")
+	}
+
+	return dates, nil
+}
+
+// countBefore counts the dates the rule gives before t, excluded ones
+// included.
+func (r RRule) countBefore(start, t time.Time) int {
+	n := 0
+	r.each(start, func(o time.Time) bool {
+		if !o.Before(t) {
+			return false
+		}
+		n++
+		return true
+	})
+	return n
+}
+
+// each calls yield with the rule's dates in order, until the rule ends or
+// yield returns false. Periods are given up on after a hundred years, so a
+// rule that can never match does not loop forever.
+func (r RRule) each(start time.Time, yield func(time.Time) bool) {
+	limit := start.AddDate(100, 0, 0)
+	n := 0
+
+	for k := 0; ; k++ {
+		period, candidates := r.period(start, k)
+		if period.After(limit) {
+			return
+		}
+
+		for _, t := range candidates {
+			if t.Before(start) {
+				continue
+			}
+			if !r.until.IsZero() && t.After(r.until) {
+				return
+			}
+
+			n++
+			if !yield(t) || (r.count > 0 && n == r.count) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the start of the k-th period of the rule, counting in
+// intervals from the one start falls in, and the dates in it that match the
+// rule, in order. Every date keeps start's time of day.
+func (r RRule) period(start time.Time, k int) (time.Time, []time.Time) {
+	switch r.freq {
+	case "DAILY":
+		day := start.AddDate(0, 0, k*r.interval)
+		if len(r.byDay) > 0 && !slices.ContainsFunc(r.byDay, func(wn weekdayNum) bool { return wn.day == day.Weekday() }) {
+			return day, nil
+		}
+		return day, []time.Time{day}
+
+	case "WEEKLY":
+		offset := func(d time.Weekday) int { return (int(d) - int(r.weekStart) + 7) % 7 }
+
+		week := start.AddDate(0, 0, 7*k*r.interval-offset(start.Weekday()))
+
+		offsets := []int{offset(start.Weekday())}
+		if len(r.byDay) > 0 {
+			offsets = offsets[:0]
+			for _, wn := range r.byDay {
+				offsets = append(offsets, offset(wn.day))
+			}
+			slices.Sort(offsets)
+			offsets = slices.Compact(offsets)
+		}
+
+		dates := make([]time.Time, len(offsets))
+		for i, o := range offsets {
+			dates[i] = week.AddDate(0, 0, o)
+		}
+		return week, dates
+
+	case "MONTHLY":
+		first := time.Date(start.Year(), start.Month()+time.Month(k*r.interval), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+
+		var dates []time.Time
+		for _, day := range r.monthDays(first, start.Day()) {
+			dates = append(dates, first.AddDate(0, 0, day-1))
+		}
+		return first, dates
+
+	default:
+		year := start.Year() + k*r.interval
+		day := time.Date(year, start.Month(), start.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		period := time.Date(year, time.January, 1, 0, 0, 0, 0, start.Location())
+
+		// February 29th only comes round in leap years.
+		if day.Day() != start.Day() {
+			return period, nil
+		}
+		return period, []time.Time{day}
+	}
+}
+
+// monthDays returns the days of the month starting at first that match
+// BYMONTHDAY and BYDAY, or startDay, the day of the month the series started
+// on, when the rule has neither.
+func (r RRule) monthDays(first time.Time, startDay int) []int {
+	last := first.AddDate(0, 1, -1).Day()
+
+	var byMonthDay, byDay []int
+
+	for _, day := range r.byMonthDay {
+		if day < 0 {
+			day = last + day + 1
+		}
+		if day >= 1 && day <= last {
+			byMonthDay = append(byMonthDay, day)
+		}
+	}
+
+	for _, wn := range r.byDay {
+		var days []int
+		for day := 1 + (int(wn.day)-int(first.Weekday())+7)%7; day <= last; day += 7 {
+			days = append(days, day)
+		}
+
+		switch {
+		case wn.n == 0:
+			byDay = append(byDay, days...)
+		case wn.n > 0 && wn.n <= len(days):
+			byDay = append(byDay, days[wn.n-1])
+		case wn.n < 0 && -wn.n <= len(days):
+			byDay = append(byDay, days[len(days)+wn.n])
+		}
+	}
+
+	var days []int
+	switch {
+	case len(r.byMonthDay) > 0 && len(r.byDay) > 0:
+		for _, day := range byMonthDay {
+			if slices.Contains(byDay, day) {
+				days = append(days, day)
+			}
+		}
+	case len(r.byMonthDay) > 0:
+		days = byMonthDay
+	case len(r.byDay) > 0:
+		days = byDay
+	case startDay <= last:
+		days = []int{startDay}
+	}
+
+	slices.Sort(days)
+	return slices.Compact(days)
+}
+
+// CreateSeries adds an event for each of dates, as occurrences of a new series
+// following rule. Like CreateEvent, every occurrence starts as a draft.
+func (es EventModel) CreateSeries(event *Event, rule RRule, dates []time.Time) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var exdates []time.Time
+	if event.Recurrence != nil {
+		exdates = event.Recurrence.ExDates
+	}
+
+	event.lowercaseEmails()
+
+	seriesID := primitive.NewObjectID()
+	now := time.Now()
+
+	events := make([]Event, len(dates))
+	docs := make([]any, len(dates))
+
+	for i, date := range dates {
+		occurrence := *event
+		occurrence.ID = primitive.NewObjectID()
+		occurrence.Date = date
+		occurrence.CreatedAt = now
+		occurrence.UpdatedAt = now
+		occurrence.Status = StatusDraft
+		occurrence.StatusReason = ""
+		occurrence.StatusHistory = nil
+		occurrence.NumberOfApplications = 0
+		occurrence.Recurrence = &Recurrence{
+			SeriesID:   seriesID,
+			RRule:      rule.String(),
+			Start:      event.Date,
+			Occurrence: date,
+			ExDates:    exdates,
+		}
+
+		events[i] = occurrence
+		docs[i] = occurrence
+	}
+
+	_, err := es.collection.InsertMany(ctx, docs)
+	if err != nil {
+		// Occurrences inserted before the failure would be a series with
+		// holes in it, so they go too.
+		es.collection.DeleteMany(context.Background(), bson.M{"recurrence.series_id": seriesID})
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// DeleteSeries removes every occurrence of a series. It is how a series that
+// could not be set up in full is undone.
+func (es EventModel) DeleteSeries(seriesID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := es.collection.DeleteMany(ctx, bson.M{"recurrence.series_id": seriesID})
+	return err
+}
+
+// UpdateSeries applies the changes in event to occurrence and the occurrences
+// after it (ScopeFollowing) or to the whole series (ScopeAll), and returns the
+// occurrences it changed. A new date moves each of them by as much as it moves
+// occurrence. Editing the following occurrences splits them off into a series
+// of their own, the way calendar apps do, so that later edits of the earlier
+// part leave them alone. Completed and cancelled occurrences are not changed.
+//
+// As with UpdateEvent, an edit by an organizer, named by editor, sends the
+// approved or published occurrences back for review. Occurrences can have
+// different organizers, so such an edit fails with ErrNotOrganizer unless
+// editor organizes every occurrence it would change. It returns
+// ErrEditConflict when there is no occurrence left to change.
+func (es EventModel) UpdateSeries(occurrence *Event, event *Event, scope, editor string) ([]Event, error) {
+	rec := occurrence.Recurrence
+	if rec == nil {
+		return nil, ErrNotRecurring
+	}
+
+	rule, err := ParseRRule(rec.RRule)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var shift time.Duration
+	if !event.Date.IsZero() {
+		shift = event.Date.Sub(occurrence.Date)
+	}
+
+	event.lowercaseEmails()
+	event.UpdatedAt = time.Now()
+
+	fields, err := updatableFields(event, "date", "created_at", "recurrence")
+	if err != nil {
+		return nil, err
+	}
+
+	// The update is a pipeline so dates can be moved relative to their own
+	// value.
+	set := literals(fields)
+	if editor != "" {
+		maps.Copy(set, sendBackForReview(editor, event.UpdatedAt))
+	}
+
+	move := func(expr any) bson.M {
+		return bson.M{"$add": bson.A{expr, shift.Milliseconds()}}
+	}
+
+	exdates := any(bson.M{"$ifNull": bson.A{"$recurrence.exdates", bson.A{}}})
+
+	set["date"] = move("$date")
+	set["recurrence.occurrence"] = move("$recurrence.occurrence")
+
+	filter := bson.M{
+		"recurrence.series_id": rec.SeriesID,
+		"status":               bson.M{"$nin": finishedStatuses},
+	}
+	seriesID := rec.SeriesID
+
+	switch scope {
+	case ScopeFollowing:
+		following := rule
+		if following.count > 0 {
+			following.count -= rule.countBefore(rec.Start, rec.Occurrence)
+		}
+		if !following.until.IsZero() {
+			following.until = following.until.Add(shift)
+		}
+
+		seriesID = primitive.NewObjectID()
+
+		filter["recurrence.occurrence"] = bson.M{"$gte": rec.Occurrence}
+		exdates = bson.M{"$filter": bson.M{"input": exdates, "cond": bson.M{"$gte": bson.A{"$$this", rec.Occurrence}}}}
+
+		set["recurrence.series_id"] = bson.M{"$literal": seriesID}
+		set["recurrence.rrule"] = bson.M{"$literal": following.String()}
+		set["recurrence.start"] = bson.M{"$literal": rec.Occurrence.Add(shift)}
+
+	case ScopeAll:
+		if !rule.until.IsZero() {
+			rule.until = rule.until.Add(shift)
+		}
+
+		set["recurrence.rrule"] = bson.M{"$literal": rule.String()}
+		set["recurrence.start"] = move("$recurrence.start")
+
+	default:
+		return nil, fmt.Errorf("unknown scope %q", scope)
+	}
+
+	set["recurrence.exdates"] = bson.M{"$map": bson.M{"input": exdates, "in": move("$$this")}}
+
+	err = es.requireOrganizer(ctx, filter, editor)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := es.collection.UpdateMany(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: set}}})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, ErrEditConflict
+	}
+
+	if scope == ScopeFollowing {
+		// What is left of the old series now ends before occurrence.
+		earlier := rule
+		earlier.count = 0
+		earlier.until = rec.Occurrence.Add(-time.Second)
+
+		_, err = es.collection.UpdateMany(ctx,
+			bson.M{"recurrence.series_id": rec.SeriesID},
+			bson.M{
+				"$set":  bson.M{"recurrence.rrule": earlier.String()},
+				"$pull": bson.M{"recurrence.exdates": bson.M{"$gte": rec.Occurrence}},
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cursor, err := es.collection.Find(ctx,
+		bson.M{"recurrence.series_id": seriesID, "updated_at": event.UpdatedAt},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []Event{}
+	err = cursor.All(ctx, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// requireOrganizer returns ErrNotOrganizer when an occurrence matching filter
+// does not list email among its organizers, and narrows filter down to the
+// occurrences email organizes in case they change in the meantime. An empty
+// email, as for an admin, is let through.
+func (es EventModel) requireOrganizer(ctx context.Context, filter bson.M, email string) error {
+	if email == "" {
+		return nil
+	}
+
+	email = strings.ToLower(email)
+
+	others := maps.Clone(filter)
+	others["organizers.email"] = bson.M{"$ne": email}
+
+	count, err := es.collection.CountDocuments(ctx, others, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrNotOrganizer
+	}
+
+	filter["organizers.email"] = email
+	return nil
+}
+
+// ExcludeOccurrence removes an occurrence from its series, recording its date
+// as an EXDATE of the series. Its applications are the caller's to remove,
+// once the attendees have been told.
+func (es EventModel) ExcludeOccurrence(occurrence *Event) error {
+	rec := occurrence.Recurrence
+	if rec == nil {
+		return ErrNotRecurring
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := es.collection.DeleteOne(ctx, bson.M{"_id": occurrence.ID})
+	if err != nil {
+		return err
+	}
+
+	_, err = es.collection.UpdateMany(ctx,
+		bson.M{"recurrence.series_id": rec.SeriesID},
+		bson.M{"$addToSet": bson.M{"recurrence.exdates": rec.Occurrence}},
+	)
+	return err
+}
@@ -0,0 +1,581 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// tuesday is the start of most series below, Tuesday November 3rd 2026.
+var tuesday = time.Date(2026, time.November, 3, 18, 0, 0, 0, time.UTC)
+
+// on returns day at tuesday's time of day.
+func on(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 18, 0, 0, 0, time.UTC)
+}
+
+func mustParseRRule(t *testing.T, s string) RRule {
+	t.Helper()
+
+	rule, err := ParseRRule(s)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", s, err)
+	}
+	return rule
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+		err      string
+	}{
+		{rule: "FREQ=WEEKLY;COUNT=10;BYDAY=TU", expected: "FREQ=WEEKLY;COUNT=10;BYDAY=TU"},
+		{rule: "RRULE:freq=monthly;byday=-1fr;count=4", expected: "FREQ=MONTHLY;COUNT=4;BYDAY=-1FR"},
+		{rule: "FREQ=DAILY;INTERVAL=1;UNTIL=20261231", expected: "FREQ=DAILY;UNTIL=20261231T235959Z"},
+		{rule: "FREQ=DAILY;UNTIL=20261231T090000Z", expected: "FREQ=DAILY;UNTIL=20261231T090000Z"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;COUNT=3;BYDAY=TU,SU;WKST=SU", expected: "FREQ=WEEKLY;INTERVAL=2;COUNT=3;BYDAY=TU,SU;WKST=SU"},
+		{rule: "FREQ=MONTHLY;COUNT=2;BYMONTHDAY=1,-1", expected: "FREQ=MONTHLY;COUNT=2;BYMONTHDAY=1,-1"},
+		{rule: "FREQ=YEARLY;COUNT=5", expected: "FREQ=YEARLY;COUNT=5"},
+
+		{rule: "COUNT=3", err: "FREQ must be set"},
+		{rule: "FREQ=HOURLY;COUNT=3", err: "FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY"},
+		{rule: "FREQ=WEEKLY;BYDAY=TU", err: "must set COUNT or UNTIL"},
+		{rule: "FREQ=DAILY;COUNT=3;COUNT=4", err: "COUNT is set more than once"},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20261231", err: "COUNT and UNTIL cannot both be set"},
+		{rule: "FREQ=DAILY;COUNT=0", err: "COUNT must be between 1 and 365"},
+		{rule: "FREQ=DAILY;COUNT=366", err: "COUNT must be between 1 and 365"},
+		{rule: "FREQ=DAILY;INTERVAL=-1;COUNT=3", err: "INTERVAL must be a positive number"},
+		{rule: "FREQ=DAILY;UNTIL=tomorrow", err: "UNTIL must be a date such as 20250131 or 20250131T090000Z"},
+		{rule: "FREQ=WEEKLY;COUNT=3;BYDAY=TUE", err: "BYDAY must list days such as MO,TU or, monthly, 1MO and -1FR"},
+		{rule: "FREQ=WEEKLY;COUNT=3;BYDAY=1MO", err: "BYDAY can only number days, as in 1MO, with FREQ=MONTHLY"},
+		{rule: "FREQ=MONTHLY;COUNT=3;BYDAY=6MO", err: "BYDAY can only number days from -5 to 5, as in 1MO or -1FR"},
+		{rule: "FREQ=YEARLY;COUNT=3;BYDAY=MO", err: "BYDAY cannot be used with FREQ=YEARLY"},
+		{rule: "FREQ=WEEKLY;COUNT=3;BYMONTHDAY=1", err: "BYMONTHDAY can only be used with FREQ=MONTHLY"},
+		{rule: "FREQ=MONTHLY;COUNT=3;BYMONTHDAY=32", err: "BYMONTHDAY must list days of the month from 1 to 31, or -31 to -1"},
+		{rule: "FREQ=WEEKLY;COUNT=3;WKST=XX", err: "WKST must be a day such as MO or SU"},
+		{rule: "FREQ=DAILY;COUNT=3;BYSETPOS=1", err: "BYSETPOS is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, rule.String())
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		exdates  []time.Time
+		expected []time.Time
+		err      string
+	}{
+		{
+			name:     "Weekly On Two Days",
+			rule:     "FREQ=WEEKLY;COUNT=4;BYDAY=TU,TH",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 5), on(2026, 11, 10), on(2026, 11, 12)},
+		},
+		{
+			// Monday the 2nd falls before the start, so it is not counted.
+			name:     "Weekly Starting Mid Week",
+			rule:     "FREQ=WEEKLY;COUNT=3;BYDAY=MO,TU",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 9), on(2026, 11, 10)},
+		},
+		{
+			name:     "Every Other Week",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 17), on(2026, 12, 1)},
+		},
+		{
+			// The example from RFC 5545: the week start decides which
+			// Sunday goes with which Tuesday.
+			name:     "Every Other Week Starting Monday",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 8), on(2026, 11, 17), on(2026, 11, 22)},
+		},
+		{
+			name:     "Every Other Week Starting Sunday",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 15), on(2026, 11, 17), on(2026, 11, 29)},
+		},
+		{
+			name:     "Daily Until Date",
+			rule:     "FREQ=DAILY;UNTIL=20261106",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 4), on(2026, 11, 5), on(2026, 11, 6)},
+		},
+		{
+			name:     "Daily Until Time",
+			rule:     "FREQ=DAILY;UNTIL=20261106T120000Z",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 4), on(2026, 11, 5)},
+		},
+		{
+			// Days BYDAY leaves out do not count towards COUNT.
+			name:     "Daily On Weekdays",
+			rule:     "FREQ=DAILY;COUNT=5;BYDAY=MO,WE,FR",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 4), on(2026, 11, 6), on(2026, 11, 9), on(2026, 11, 11), on(2026, 11, 13)},
+		},
+		{
+			name:     "Last Friday Of The Month",
+			rule:     "FREQ=MONTHLY;COUNT=4;BYDAY=-1FR",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 27), on(2026, 12, 25), on(2027, 1, 29), on(2027, 2, 26)},
+		},
+		{
+			name:     "Friday The Thirteenth",
+			rule:     "FREQ=MONTHLY;COUNT=3;BYDAY=FR;BYMONTHDAY=13",
+			start:    tuesday,
+			expected: []time.Time{on(2026, 11, 13), on(2027, 8, 13), on(2028, 10, 13)},
+		},
+		{
+			// Months without a 31st are skipped, as RFC 5545 has it.
+			name:     "Monthly On The 31st",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			start:    on(2027, 1, 31),
+			expected: []time.Time{on(2027, 1, 31), on(2027, 3, 31), on(2027, 5, 31)},
+		},
+		{
+			name:     "Last Day Of The Month",
+			rule:     "FREQ=MONTHLY;COUNT=3;BYMONTHDAY=-1",
+			start:    on(2027, 1, 31),
+			expected: []time.Time{on(2027, 1, 31), on(2027, 2, 28), on(2027, 3, 31)},
+		},
+		{
+			name:     "Yearly On February 29th",
+			rule:     "FREQ=YEARLY;COUNT=3",
+			start:    on(2028, 2, 29),
+			expected: []time.Time{on(2028, 2, 29), on(2032, 2, 29), on(2036, 2, 29)},
+		},
+		{
+			// Excluded dates still count towards COUNT.
+			name:     "Excluded Date",
+			rule:     "FREQ=WEEKLY;COUNT=3;BYDAY=TU",
+			start:    tuesday,
+			exdates:  []time.Time{on(2026, 11, 10)},
+			expected: []time.Time{on(2026, 11, 3), on(2026, 11, 17)},
+		},
+		{
+			name:  "Too Many",
+			rule:  "FREQ=DAILY;UNTIL=20301231",
+			start: tuesday,
+			err:   "must not have more than 365 occurrences",
+		},
+		{
+			name:    "All Excluded",
+			rule:    "FREQ=DAILY;COUNT=1",
+			start:   tuesday,
+			exdates: []time.Time{tuesday},
+			err:     "must have at least one occurrence",
+		},
+		{
+			// Every February, which never has a 30th; the search gives up
+			// after a hundred years.
+			name:  "Never Matches",
+			rule:  "FREQ=MONTHLY;INTERVAL=12;COUNT=2;BYMONTHDAY=30",
+			start: on(2027, 2, 1),
+			err:   "must have at least one occurrence",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dates, err := mustParseRRule(t, tt.rule).Occurrences(tt.start, tt.exdates)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, dates)
+		})
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       string
+		start      time.Time
+		k          int
+		period     time.Time
+		candidates []time.Time
+	}{
+		{
+			name:       "Daily",
+			rule:       "FREQ=DAILY;INTERVAL=3;COUNT=5",
+			start:      tuesday,
+			k:          2,
+			period:     on(2026, 11, 9),
+			candidates: []time.Time{on(2026, 11, 9)},
+		},
+		{
+			name:   "Daily On Another Weekday",
+			rule:   "FREQ=DAILY;COUNT=5;BYDAY=MO",
+			start:  tuesday,
+			k:      1,
+			period: on(2026, 11, 4),
+		},
+		{
+			name:       "Weekly From Monday",
+			rule:       "FREQ=WEEKLY;COUNT=5;BYDAY=SU,MO",
+			start:      tuesday,
+			k:          1,
+			period:     on(2026, 11, 9),
+			candidates: []time.Time{on(2026, 11, 9), on(2026, 11, 15)},
+		},
+		{
+			name:       "Weekly From Sunday",
+			rule:       "FREQ=WEEKLY;COUNT=5;BYDAY=SU,MO;WKST=SU",
+			start:      tuesday,
+			k:          0,
+			period:     on(2026, 11, 1),
+			candidates: []time.Time{on(2026, 11, 1), on(2026, 11, 2)},
+		},
+		{
+			name:       "Monthly Into Next Year",
+			rule:       "FREQ=MONTHLY;INTERVAL=2;COUNT=5",
+			start:      tuesday,
+			k:          1,
+			period:     on(2027, 1, 1),
+			candidates: []time.Time{on(2027, 1, 3)},
+		},
+		{
+			name:   "Yearly Without A Leap Day",
+			rule:   "FREQ=YEARLY;COUNT=5",
+			start:  on(2028, 2, 29),
+			k:      1,
+			period: time.Date(2029, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, candidates := mustParseRRule(t, tt.rule).period(tt.start, tt.k)
+
+			assert.Equal(t, tt.period, period)
+			assert.Equal(t, tt.candidates, candidates)
+		})
+	}
+}
+
+func TestMonthDays(t *testing.T) {
+	// November 2026 starts on a Sunday and has 30 days.
+	november := on(2026, 11, 1)
+
+	tests := []struct {
+		rule     string
+		startDay int
+		expected []int
+	}{
+		{rule: "FREQ=MONTHLY;COUNT=1", startDay: 15, expected: []int{15}},
+		{rule: "FREQ=MONTHLY;COUNT=1", startDay: 31, expected: nil},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYDAY=1MO", expected: []int{2}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYDAY=-1FR", expected: []int{27}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYDAY=5MO", expected: []int{30}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYDAY=5FR", expected: nil},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYDAY=TU,TH", expected: []int{3, 5, 10, 12, 17, 19, 24, 26}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYMONTHDAY=-1", expected: []int{30}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYMONTHDAY=31", expected: nil},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYMONTHDAY=-1,1,30", expected: []int{1, 30}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYMONTHDAY=13;BYDAY=FR", expected: []int{13}},
+		{rule: "FREQ=MONTHLY;COUNT=1;BYMONTHDAY=14;BYDAY=FR", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mustParseRRule(t, tt.rule).monthDays(november, tt.startDay))
+		})
+	}
+}
+
+func TestCountBefore(t *testing.T) {
+	rule := mustParseRRule(t, "FREQ=WEEKLY;COUNT=10;BYDAY=TU")
+
+	tests := []struct {
+		name     string
+		before   time.Time
+		expected int
+	}{
+		{name: "Start", before: tuesday, expected: 0},
+		{name: "Third Occurrence", before: on(2026, 11, 17), expected: 2},
+		{name: "Between Occurrences", before: on(2026, 11, 19), expected: 3},
+		{name: "After The Last", before: on(2027, 6, 1), expected: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rule.countBefore(tuesday, tt.before))
+		})
+	}
+}
+
+// newTestSeries stores a weekly series of six Tuesdays from tuesday, with the
+// second one excluded, organized by John Doe.
+func newTestSeries(t *testing.T, models Models) []Event {
+	t.Helper()
+
+	rule := mustParseRRule(t, "FREQ=WEEKLY;COUNT=6;BYDAY=TU")
+	exdates := []time.Time{on(2026, 11, 10)}
+
+	dates, err := rule.Occurrences(tuesday, exdates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := &Event{
+		Name:       "Go Workshop",
+		Date:       tuesday,
+		Organizers: []Organizer{{Name: "John Doe", Email: "John.Doe@example.com"}},
+		Recurrence: &Recurrence{ExDates: exdates},
+	}
+
+	events, err := models.Event.CreateSeries(event, rule, dates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
+
+func TestUpdateSeries(t *testing.T) {
+	db := testDatabase(t)
+	models := NewModels(db)
+
+	t.Run("Following", func(t *testing.T) {
+		events := newTestSeries(t, models)
+		assert.Len(t, events, 5)
+
+		// Edit the third date the rule gives, November 24th, and the ones
+		// after it, an hour later than before.
+		occurrence := &events[2]
+		updated, err := models.Event.UpdateSeries(occurrence, &Event{Name: "Advanced Go Workshop", Date: occurrence.Date.Add(time.Hour)}, ScopeFollowing, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, updated, 3)
+		for i, event := range updated {
+			assert.Equal(t, "Advanced Go Workshop", event.Name)
+			assert.True(t, event.Date.Equal(events[i+2].Date.Add(time.Hour)), "occurrence %d is on %s", i, event.Date)
+			assert.NotEqual(t, occurrence.Recurrence.SeriesID, event.Recurrence.SeriesID)
+			assert.Equal(t, "FREQ=WEEKLY;COUNT=3;BYDAY=TU", event.Recurrence.RRule)
+			assert.True(t, event.Recurrence.Start.Equal(on(2026, 11, 24).Add(time.Hour)))
+			assert.Empty(t, event.Recurrence.ExDates)
+		}
+
+		// The rule of the new series gives the same dates.
+		rule := mustParseRRule(t, updated[0].Recurrence.RRule)
+		dates, err := rule.Occurrences(updated[0].Recurrence.Start, nil)
+		assert.NoError(t, err)
+		for i, date := range dates {
+			assert.True(t, date.Equal(updated[i].Date))
+		}
+
+		// What is left of the old series ends before the split and keeps
+		// its exclusion.
+		for _, event := range events[:2] {
+			stored, err := models.Event.GetEventByID(event.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, "Go Workshop", stored.Name)
+			assert.Equal(t, "FREQ=WEEKLY;UNTIL=20261124T175959Z;BYDAY=TU", stored.Recurrence.RRule)
+			assert.Len(t, stored.Recurrence.ExDates, 1)
+			assert.True(t, stored.Recurrence.ExDates[0].Equal(on(2026, 11, 10)))
+		}
+	})
+
+	t.Run("All", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		updated, err := models.Event.UpdateSeries(&events[1], &Event{Name: "Advanced Go Workshop", Date: events[1].Date.Add(-time.Hour)}, ScopeAll, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, updated, len(events))
+		for i, event := range updated {
+			assert.Equal(t, "Advanced Go Workshop", event.Name)
+			assert.True(t, event.Date.Equal(events[i].Date.Add(-time.Hour)))
+			assert.Equal(t, events[0].Recurrence.SeriesID, event.Recurrence.SeriesID)
+			assert.True(t, event.Recurrence.Start.Equal(tuesday.Add(-time.Hour)))
+			assert.Len(t, event.Recurrence.ExDates, 1)
+			assert.True(t, event.Recurrence.ExDates[0].Equal(on(2026, 11, 10).Add(-time.Hour)))
+		}
+	})
+
+	t.Run("Sends Reviewed Occurrences Back", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		_, err := db.Collection("events").UpdateOne(context.Background(), bson.M{"_id": events[0].ID}, bson.M{"$set": bson.M{"status": StatusPublished}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		updated, err := models.Event.UpdateSeries(&events[0], &Event{Name: "Advanced Go Workshop"}, ScopeAll, "john.doe@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, StatusPendingReview, updated[0].Status)
+		assert.Len(t, updated[0].StatusHistory, 1)
+		assert.Equal(t, StatusPublished, updated[0].StatusHistory[0].From)
+		assert.Equal(t, "john.doe@example.com", updated[0].StatusHistory[0].By)
+
+		// Drafts stay drafts.
+		for _, event := range updated[1:] {
+			assert.Equal(t, StatusDraft, event.Status)
+			assert.Empty(t, event.StatusHistory)
+		}
+	})
+
+	t.Run("Leaves Finished Occurrences Alone", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		setStatus(t, db, events[0].ID, StatusCompleted)
+		setStatus(t, db, events[3].ID, StatusCancelled)
+
+		updated, err := models.Event.UpdateSeries(&events[1], &Event{Name: "Advanced Go Workshop", Date: events[1].Date.Add(time.Hour)}, ScopeAll, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, updated, 3)
+		for _, event := range updated {
+			assert.NotEqual(t, events[0].ID, event.ID)
+			assert.NotEqual(t, events[3].ID, event.ID)
+		}
+
+		for _, event := range []Event{events[0], events[3]} {
+			stored, err := models.Event.GetEventByID(event.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, "Go Workshop", stored.Name)
+			assert.True(t, stored.Date.Equal(event.Date))
+		}
+	})
+
+	t.Run("Refuses Occurrences Organized By Others", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		_, err := db.Collection("events").UpdateOne(context.Background(),
+			bson.M{"_id": events[3].ID},
+			bson.M{"$set": bson.M{"organizers": []Organizer{{Name: "Jane Doe", Email: "jane.doe@example.com"}}}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = models.Event.UpdateSeries(&events[0], &Event{Name: "Advanced Go Workshop"}, ScopeAll, "john.doe@example.com")
+		assert.ErrorIs(t, err, ErrNotOrganizer)
+
+		// Nothing is changed.
+		for _, event := range events {
+			stored, err := models.Event.GetEventByID(event.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "Go Workshop", stored.Name)
+		}
+
+		// The occurrences after the one organized by someone else are still
+		// John's to edit, in whatever case his email comes.
+		updated, err := models.Event.UpdateSeries(&events[4], &Event{Name: "Advanced Go Workshop"}, ScopeFollowing, "JOHN.DOE@example.com")
+		assert.NoError(t, err)
+		assert.Len(t, updated, 1)
+	})
+
+	t.Run("Nothing Left To Change", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		for _, event := range events {
+			setStatus(t, db, event.ID, StatusCompleted)
+		}
+
+		_, err := models.Event.UpdateSeries(&events[0], &Event{Name: "Advanced Go Workshop"}, ScopeAll, "")
+		assert.ErrorIs(t, err, ErrEditConflict)
+	})
+}
+
+func TestChangeSeriesStatus(t *testing.T) {
+	db := testDatabase(t)
+	models := NewModels(db)
+
+	change := StatusChange{From: StatusDraft, To: StatusPendingReview, By: "john.doe@example.com", At: time.Now()}
+
+	t.Run("Organizer", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		changed, err := models.Event.ChangeSeriesStatus(&events[2], change, ScopeFollowing, "John.Doe@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, changed, 3)
+		for _, event := range changed {
+			assert.Equal(t, StatusPendingReview, event.Status)
+		}
+	})
+
+	t.Run("Refuses Occurrences Organized By Others", func(t *testing.T) {
+		events := newTestSeries(t, models)
+
+		_, err := db.Collection("events").UpdateOne(context.Background(),
+			bson.M{"_id": events[0].ID},
+			bson.M{"$set": bson.M{"organizers": []Organizer{{Name: "Jane Doe", Email: "jane.doe@example.com"}}}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = models.Event.ChangeSeriesStatus(&events[1], change, ScopeAll, "john.doe@example.com")
+		assert.ErrorIs(t, err, ErrNotOrganizer)
+
+		stored, err := models.Event.GetEventByID(events[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, StatusDraft, stored.Status)
+
+		// Admins review the whole series whoever organizes it.
+		changed, err := models.Event.ChangeSeriesStatus(&events[1], change, ScopeAll, "")
+		assert.NoError(t, err)
+		assert.Len(t, changed, len(events))
+	})
+}
+
+// setStatus gives the event with id a status directly, bypassing the checks of
+// ChangeStatus.
+func setStatus(t *testing.T, db *mongo.Database, id primitive.ObjectID, status string) {
+	t.Helper()
+
+	_, err := db.Collection("events").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"status": status}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
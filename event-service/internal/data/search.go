@@ -0,0 +1,427 @@
+package data
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// eventTextIndex is the name of the collection's only text index. Mongo
+	// allows one per collection, so CreateIndexes drops any other.
+	eventTextIndex = "events_text"
+
+	maxSearchTerms = 10
+
+	// maxSearchCandidates bounds how many events the text index and the
+	// typo-tolerant prefix match each return, as the two are merged, sorted and
+	// paginated in memory.
+	maxSearchCandidates = 1000
+
+	snippetLength = 160
+)
+
+// SearchResult is an event matching a search, with its relevance score and
+// the matching fields as HTML-escaped snippets in which the matched words are
+// wrapped in <mark> tags.
+type SearchResult struct {
+	Event      Event          `bson:",inline" json:"event"`
+	Score      float64        `bson:"score" json:"score"`
+	Highlights map[string]any `bson:"-" json:"highlights"`
+}
+
+// searchTerms splits a query into lower-case words.
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	return terms
+}
+
+// ValidateSearchQuery returns the problem with a search query, or an empty
+// string.
+func ValidateSearchQuery(query string) string {
+	switch {
+	case strings.TrimSpace(query) == "":
+		return "must be provided"
+	case len(query) > 200:
+		return "must not be more than 200 bytes long"
+	case len(searchTerms(query)) == 0:
+		return "must contain at least one letter or digit"
+	}
+
+	return ""
+}
+
+// SearchEvents finds the events matching query and filters, best match first.
+// Whole words are looked up in the text index, which also matches other forms
+// of a word ("conferences" finds "conference"). Events are also matched on word
+// prefixes allowing for a typo or two, so that the last word, which the user
+// may still be typing, and mistyped words find them as well. Both sets of
+// matches are merged, adding up the scores of an event found by both, and are
+// capped at maxSearchCandidates each. The filters' sort is ignored.
+func (es EventModel) SearchEvents(query string, filters EventFilters) ([]SearchResult, Metadata, error) {
+	terms := searchTerms(query)
+
+	textMatches, err := es.textSearch(query, terms, filters)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	prefixMatches, err := es.fuzzySearch(terms, filters)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	results := mergeResults(textMatches, prefixMatches)
+	metadata := calculateMetadata(len(results), filters.Page, filters.PageSize)
+
+	start := min(int(filters.skip()), len(results))
+	end := min(start+filters.PageSize, len(results))
+
+	return results[start:end], metadata, nil
+}
+
+func (es EventModel) textSearch(query string, terms []string, filters EventFilters) ([]SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := filters.query()
+	filter["$text"] = bson.M{"$search": query}
+
+	score := bson.M{"$meta": "textScore"}
+
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetLimit(maxSearchCandidates)
+
+	cursor, err := es.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []SearchResult{}
+	err = cursor.All(ctx, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		_, results[i].Highlights = matchEvent(&results[i].Event, terms)
+	}
+
+	return results, nil
+}
+
+func (es EventModel) fuzzySearch(terms []string, filters EventFilters) ([]SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	// Narrow the candidates down in the database to events with a word that
+	// starts like each term. A typo in those first letters is not forgiven.
+	filter := filters.query()
+	and, _ := filter["$and"].(bson.A)
+
+	for _, term := range terms {
+		prefix := bson.M{"$regex": wordPrefixPattern(fuzzyPrefix(term)), "$options": "i"}
+
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"name": prefix},
+			bson.M{"description": prefix},
+			bson.M{"organizers.name": prefix},
+		}})
+	}
+	filter["$and"] = and
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(maxSearchCandidates)
+
+	cursor, err := es.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []Event
+	err = cursor.All(ctx, &candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []SearchResult{}
+
+	for i := range candidates {
+		score, highlights := matchEvent(&candidates[i], terms)
+		if score > 0 {
+			matches = append(matches, SearchResult{Event: candidates[i], Score: score, Highlights: highlights})
+		}
+	}
+
+	return matches, nil
+}
+
+// wordPrefixPattern is a regular expression matching prefix at the start of a
+// word. \b only knows ASCII letters, so it would find "cole" in "école" and
+// miss "éc" at its start; the boundary is spelled out with Unicode classes.
+func wordPrefixPattern(prefix string) string {
+	return `(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(prefix)
+}
+
+// mergeResults combines the text index matches with the prefix matches, best
+// first. An event found by both is listed once with the sum of its scores;
+// ties keep the text index matches first, in the order they were found.
+func mergeResults(textMatches, prefixMatches []SearchResult) []SearchResult {
+	results := make([]SearchResult, 0, len(textMatches)+len(prefixMatches))
+	index := make(map[primitive.ObjectID]int, len(textMatches))
+
+	for _, match := range textMatches {
+		index[match.Event.ID] = len(results)
+		results = append(results, match)
+	}
+
+	for _, match := range prefixMatches {
+		if i, ok := index[match.Event.ID]; ok {
+			results[i].Score += match.Score
+			continue
+		}
+		results = append(results, match)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+// fuzzyPrefix is the part of a term that has to be typed correctly.
+func fuzzyPrefix(term string) string {
+	runes := []rune(term)
+
+	n := 2
+	if len(runes) > 4 {
+		n = 3
+	}
+
+	return string(runes[:min(n, len(runes))])
+}
+
+// searchField is a piece of an event's text that search looks at, weighted by
+// how much a match in it counts.
+type searchField struct {
+	name   string
+	text   string
+	weight float64
+}
+
+// matchEvent scores an event against every term and returns its highlighted
+// fields. The score is zero unless every term matches a word somewhere.
+func matchEvent(event *Event, terms []string) (float64, map[string]any) {
+	fields := []searchField{
+		{name: "name", text: event.Name, weight: 3},
+		{name: "description", text: event.Description, weight: 1},
+	}
+	for _, organizer := range event.Organizers {
+		fields = append(fields, searchField{name: "organizers", text: organizer.Name, weight: 2})
+	}
+
+	best := make([]float64, len(terms))
+	highlights := map[string]any{}
+
+	for _, field := range fields {
+		snippet, scores := highlight(field.text, terms)
+		if snippet == "" {
+			continue
+		}
+
+		for i, score := range scores {
+			best[i] = max(best[i], score*field.weight)
+		}
+
+		if field.name == "organizers" {
+			names, _ := highlights["organizers"].([]string)
+			highlights["organizers"] = append(names, snippet)
+		} else {
+			highlights[field.name] = snippet
+		}
+	}
+
+	total := 0.0
+	for _, score := range best {
+		if score == 0 {
+			return 0, highlights
+		}
+		total += score
+	}
+
+	return total, highlights
+}
+
+// highlight marks the words of text that match a term and returns a snippet
+// around the first of them, or an empty string when nothing matches. It also
+// returns the best match score for each term.
+func highlight(text string, terms []string) (string, []float64) {
+	scores := make([]float64, len(terms))
+
+	type span struct{ start, end int }
+	var marks []span
+
+	runes := []rune(text)
+
+	for start := 0; start < len(runes); {
+		if !unicode.IsLetter(runes[start]) && !unicode.IsNumber(runes[start]) {
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsNumber(runes[end])) {
+			end++
+		}
+
+		word := strings.ToLower(string(runes[start:end]))
+		matched := false
+
+		for i, term := range terms {
+			if score := matchWord(word, term); score > 0 {
+				scores[i] = max(scores[i], score)
+				matched = true
+			}
+		}
+
+		if matched {
+			marks = append(marks, span{start, end})
+		}
+
+		start = end
+	}
+
+	if len(marks) == 0 {
+		return "", scores
+	}
+
+	// Keep the snippet to a window starting a little before the first match.
+	from, to := 0, len(runes)
+	if len(runes) > snippetLength {
+		from = max(0, marks[0].start-snippetLength/4)
+		for from > 0 && !unicode.IsSpace(runes[from-1]) {
+			from--
+		}
+		to = min(len(runes), from+snippetLength)
+		for to < len(runes) && !unicode.IsSpace(runes[to]) {
+			to++
+		}
+	}
+
+	var b strings.Builder
+
+	if from > 0 {
+		b.WriteString("…")
+	}
+
+	at := from
+	for _, mark := range marks {
+		if mark.start < from || mark.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[at:mark.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[mark.start:mark.end])))
+		b.WriteString("</mark>")
+		at = mark.end
+	}
+	b.WriteString(html.EscapeString(string(runes[at:to])))
+
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String(), scores
+}
+
+// matchWord scores how well a lower-case word matches a term: 1 when the word
+// starts with the term, less for each typo needed to make it do so, and 0 for
+// no match.
+func matchWord(word, term string) float64 {
+	if strings.HasPrefix(word, term) {
+		return 1
+	}
+
+	edits := allowedEdits(term)
+	if edits == 0 {
+		return 0
+	}
+
+	w, t := []rune(word), []rune(term)
+
+	best := edits + 1
+	for n := len(t) - edits; n <= len(t)+edits; n++ {
+		if n <= 0 || n > len(w) {
+			continue
+		}
+		best = min(best, editDistance(w[:n], t))
+	}
+
+	if best > edits {
+		return 0
+	}
+
+	return 1 - 0.25*float64(best)
+}
+
+// allowedEdits is how many typos a term may contain, more for longer terms.
+func allowedEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance counts the insertions, deletions, substitutions and
+// transpositions of adjacent letters that turn a into b.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
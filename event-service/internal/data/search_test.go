@@ -0,0 +1,294 @@
+package data
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFuzzyPrefix(t *testing.T) {
+	tests := []struct {
+		term     string
+		expected string
+	}{
+		{term: "a", expected: "a"},
+		{term: "go", expected: "go"},
+		{term: "jazz", expected: "ja"},
+		{term: "music", expected: "mus"},
+		{term: "école", expected: "éco"},
+		{term: "東京都", expected: "東京"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fuzzyPrefix(tt.term))
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "", b: "", expected: 0},
+		{a: "", b: "jazz", expected: 4},
+		{a: "jazz", b: "jazz", expected: 0},
+		{a: "jazz", b: "jaz", expected: 1},
+		{a: "jazz", b: "jazzy", expected: 1},
+		{a: "jazz", b: "jizz", expected: 1},
+		{a: "concert", b: "cnocert", expected: 1},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "café", b: "cafe", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, editDistance([]rune(tt.a), []rune(tt.b)))
+			assert.Equal(t, tt.expected, editDistance([]rune(tt.b), []rune(tt.a)))
+		})
+	}
+}
+
+func TestMatchWord(t *testing.T) {
+	tests := []struct {
+		name     string
+		word     string
+		term     string
+		expected float64
+	}{
+		{name: "Same Word", word: "jazz", term: "jazz", expected: 1},
+		{name: "Prefix", word: "workshop", term: "work", expected: 1},
+		{name: "Accented Prefix", word: "écologique", term: "éco", expected: 1},
+		{name: "Longer Term", word: "go", term: "gopher", expected: 0},
+		{name: "Short Term No Typos Allowed", word: "rock", term: "rok", expected: 0},
+		{name: "Four Letter Term Typo", word: "rock", term: "rick", expected: 0.75},
+		{name: "One Typo", word: "concert", term: "concret", expected: 0.75},
+		{name: "One Typo In Prefix", word: "festival", term: "fets", expected: 0.75},
+		{name: "Two Typos Too Many", word: "music", term: "mosuc", expected: 0},
+		{name: "Two Typos In Long Term", word: "conference", term: "confarance", expected: 0.5},
+		{name: "Three Typos Too Many", word: "conference", term: "cinfarance", expected: 0},
+		{name: "Missing Accent In Short Term", word: "café", term: "cafe", expected: 0.75},
+		{name: "Missing Accent", word: "événement", term: "evenement", expected: 0.5},
+		{name: "Unrelated", word: "workshop", term: "banquet", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchWord(tt.word, tt.term))
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := "Bring a laptop with Go installed and some curiosity, as we spend the afternoon " +
+		"walking through the standard library, writing small tools together and looking at how " +
+		"they are tested. At the end of the day we build a little web server from scratch and " +
+		"deploy it, so you leave with something running."
+
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		snippet  string
+		expected []float64
+	}{
+		{
+			name:     "No Match",
+			text:     "Jazz in the Park",
+			terms:    []string{"rock"},
+			snippet:  "",
+			expected: []float64{0},
+		},
+		{
+			name:     "Every Match Marked",
+			text:     "Jazz in the Park, jazz all night",
+			terms:    []string{"jazz"},
+			snippet:  "<mark>Jazz</mark> in the Park, <mark>jazz</mark> all night",
+			expected: []float64{1},
+		},
+		{
+			name:     "Best Score Per Term",
+			text:     "Go Workshop and Workshops",
+			terms:    []string{"go", "wrokshop", "rust"},
+			snippet:  "<mark>Go</mark> <mark>Workshop</mark> and <mark>Workshops</mark>",
+			expected: []float64{1, 0.75, 0},
+		},
+		{
+			name:     "Accented Words",
+			text:     "Atelier d'écologie à Montréal",
+			terms:    []string{"éco", "montreal"},
+			snippet:  "Atelier d&#39;<mark>écologie</mark> à <mark>Montréal</mark>",
+			expected: []float64{1, 0.75},
+		},
+		{
+			name:     "Escapes HTML",
+			text:     "<b>Rock</b> & Roll",
+			terms:    []string{"roll"},
+			snippet:  "&lt;b&gt;Rock&lt;/b&gt; &amp; <mark>Roll</mark>",
+			expected: []float64{1},
+		},
+		{
+			name:     "Long Text Cut Around First Match",
+			text:     long,
+			terms:    []string{"server"},
+			snippet:  "…the end of the day we build a little web <mark>server</mark> from scratch and deploy it, so you leave with something running.",
+			expected: []float64{1},
+		},
+		{
+			name:     "Matches Outside The Snippet Not Marked",
+			text:     long,
+			terms:    []string{"laptop", "running"},
+			snippet:  "Bring a <mark>laptop</mark> with Go installed and some curiosity, as we spend the afternoon walking through the standard library, writing small tools together and looking at…",
+			expected: []float64{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, scores := highlight(tt.text, tt.terms)
+			assert.Equal(t, tt.snippet, snippet)
+			assert.Equal(t, tt.expected, scores)
+		})
+	}
+}
+
+func TestWordPrefixPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		text     string
+		expected bool
+	}{
+		{name: "Start Of Text", prefix: "jaz", text: "Jazz in the Park", expected: true},
+		{name: "After A Space", prefix: "par", text: "Jazz in the Park", expected: true},
+		{name: "After Punctuation", prefix: "roc", text: "Jazz/Rock night", expected: true},
+		{name: "Inside A Word", prefix: "azz", text: "Jazz in the Park", expected: false},
+		{name: "Accented Start", prefix: "éco", text: "Atelier d'écologie", expected: true},
+		{name: "Inside An Accented Word", prefix: "col", text: "Visite de l'école", expected: false},
+		{name: "After An Accented Letter", prefix: "té", text: "Fête de la musique", expected: false},
+		{name: "Metacharacters Quoted", prefix: "c+", text: "c++ meetup", expected: true},
+		{name: "Metacharacters Not Patterns", prefix: "c+", text: "cc meetup", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Go's regular expressions read the pattern the way MongoDB's
+			// PCRE does with the i option.
+			re := regexp.MustCompile("(?i)" + wordPrefixPattern(tt.prefix))
+			assert.Equal(t, tt.expected, re.MatchString(tt.text))
+		})
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	result := func(id primitive.ObjectID, score float64) SearchResult {
+		return SearchResult{Event: Event{ID: id}, Score: score}
+	}
+
+	tests := []struct {
+		name          string
+		textMatches   []SearchResult
+		prefixMatches []SearchResult
+		expected      []SearchResult
+	}{
+		{
+			name:     "Nothing Found",
+			expected: []SearchResult{},
+		},
+		{
+			name:          "Prefix Matches Only",
+			prefixMatches: []SearchResult{result(a, 1), result(b, 3)},
+			expected:      []SearchResult{result(b, 3), result(a, 1)},
+		},
+		{
+			name:          "Found By Both Listed Once",
+			textMatches:   []SearchResult{result(a, 2), result(b, 1.5)},
+			prefixMatches: []SearchResult{result(b, 3), result(c, 1)},
+			expected:      []SearchResult{result(b, 4.5), result(a, 2), result(c, 1)},
+		},
+		{
+			name:          "Ties Keep Text Matches First",
+			textMatches:   []SearchResult{result(a, 1)},
+			prefixMatches: []SearchResult{result(b, 1)},
+			expected:      []SearchResult{result(a, 1), result(b, 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mergeResults(tt.textMatches, tt.prefixMatches))
+		})
+	}
+}
+
+func TestSearchEvents(t *testing.T) {
+	db := testDatabase(t)
+	models := NewModels(db)
+
+	err := CreateIndexes(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"Go Workshop", "Go Conference", "Rust Workshop", "Atelier d'écologie"}
+	ids := make(map[string]primitive.ObjectID, len(names))
+
+	docs := make([]any, len(names))
+	for i, name := range names {
+		ids[name] = primitive.NewObjectID()
+		docs[i] = Event{ID: ids[name], Name: name, Date: time.Now().AddDate(0, 0, i+1)}
+	}
+
+	_, err = db.Collection("events").InsertMany(context.Background(), docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "Whole Words", query: "go workshop", expected: []string{"Go Workshop", "Go Conference", "Rust Workshop"}},
+		{name: "Last Word Being Typed", query: "go work", expected: []string{"Go Workshop", "Go Conference"}},
+		{name: "Typo", query: "confrence", expected: []string{"Go Conference"}},
+		{name: "Accented Prefix", query: "éco", expected: []string{"Atelier d'écologie"}},
+		{name: "No Match", query: "banquet", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, metadata, err := models.Event.SearchEvents(tt.query, EventFilters{Page: 1, PageSize: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			found := []string{}
+			for _, result := range results {
+				found = append(found, result.Event.Name)
+			}
+
+			assert.Equal(t, tt.expected, found)
+			assert.Equal(t, len(tt.expected), metadata.TotalRecords)
+		})
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		results, metadata, err := models.Event.SearchEvents("go workshop", EventFilters{Page: 2, PageSize: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, results, 1)
+		assert.Equal(t, ids["Rust Workshop"], results[0].Event.ID)
+		assert.Equal(t, 3, metadata.TotalRecords)
+		assert.Equal(t, 2, metadata.LastPage)
+	})
+}
@@ -0,0 +1,248 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// An event starts as a draft, which its organizers submit for review. An admin
+// approves or rejects it; a rejected event can be fixed and submitted again. An
+// approved event is published by its organizers, and only then can students
+// see it and apply. A published event is finally cancelled or, once it has
+// taken place, completed.
+const (
+	StatusDraft         = "DRAFT"
+	StatusPendingReview = "PENDING_REVIEW"
+	StatusApproved      = "APPROVED"
+	StatusRejected      = "REJECTED"
+	StatusPublished     = "PUBLISHED"
+	StatusCancelled     = "CANCELLED"
+	StatusCompleted     = "COMPLETED"
+
+	// statusLegacyPending is the status events were created with before the
+	// review workflow. Such events are treated as waiting for review.
+	statusLegacyPending = "PENDING"
+)
+
+// EventStatuses lists every valid event status.
+var EventStatuses = []string{
+	StatusDraft,
+	StatusPendingReview,
+	StatusApproved,
+	StatusRejected,
+	StatusPublished,
+	StatusCancelled,
+	StatusCompleted,
+}
+
+// statusTransitions maps each status to the statuses an event can move to
+// from it.
+var statusTransitions = map[string][]string{
+	StatusDraft:         {StatusPendingReview},
+	StatusPendingReview: {StatusApproved, StatusRejected},
+	StatusRejected:      {StatusPendingReview},
+	StatusApproved:      {StatusPublished},
+	StatusPublished:     {StatusCancelled, StatusCompleted},
+}
+
+// reviewedStatuses are the statuses an admin has signed off on. An organizer's
+// edit sends an event in one of them back for review, so nothing an admin has
+// not seen stays approved or published.
+var reviewedStatuses = []string{StatusApproved, StatusPublished}
+
+// editedReason is the reason recorded when an edit sends an event back for
+// review.
+const editedReason = "edited after review"
+
+var (
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrEditConflict      = errors.New("edit conflict")
+)
+
+// StatusChange records an event moving from one status to another, who moved
+// it and why.
+type StatusChange struct {
+	From   string    `bson:"from" json:"from"`
+	To     string    `bson:"to" json:"to"`
+	Reason string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	By     string    `bson:"by" json:"by"`
+	At     time.Time `bson:"at" json:"at"`
+}
+
+// CanTransition reports whether an event may move from one status to another.
+func CanTransition(from, to string) bool {
+	if from == statusLegacyPending {
+		from = StatusPendingReview
+	}
+
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// IsOrganizer reports whether email belongs to one of the event's organizers.
+func (e *Event) IsOrganizer(email string) bool {
+	for _, organizer := range e.Organizers {
+		if strings.EqualFold(organizer.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStatus moves an event along its lifecycle and appends the change to the
+// event's status history. The update only applies while the event still has
+// change.From, so of two admins reviewing an event at once, the second gets
+// ErrEditConflict.
+func (es EventModel) ChangeStatus(id primitive.ObjectID, change StatusChange) (*Event, error) {
+	if !CanTransition(change.From, change.To) {
+		return nil, ErrInvalidTransition
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "status": change.From}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":        change.To,
+			"status_reason": change.Reason,
+			"updated_at":    change.At,
+		},
+		"$push": bson.M{"status_history": change},
+	}
+
+	result, err := es.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, ErrEditConflict
+	}
+
+	return es.GetEventByID(id)
+}
+
+// ChangeSeriesStatus makes the same change as ChangeStatus to occurrence and
+// the occurrences after it (ScopeFollowing) or to the whole series (ScopeAll),
+// so an admin can review a series at once. Only occurrences that still have
+// change.From are changed; it returns those. Unless organizer is empty, as for
+// an admin, it fails with ErrNotOrganizer when organizer does not organize
+// every one of them.
+func (es EventModel) ChangeSeriesStatus(occurrence *Event, change StatusChange, scope, organizer string) ([]Event, error) {
+	rec := occurrence.Recurrence
+	if rec == nil {
+		return nil, ErrNotRecurring
+	}
+
+	if !CanTransition(change.From, change.To) {
+		return nil, ErrInvalidTransition
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"recurrence.series_id": rec.SeriesID, "status": change.From}
+
+	switch scope {
+	case ScopeFollowing:
+		filter["recurrence.occurrence"] = bson.M{"$gte": rec.Occurrence}
+	case ScopeAll:
+	default:
+		return nil, fmt.Errorf("unknown scope %q", scope)
+	}
+
+	err := es.requireOrganizer(ctx, filter, organizer)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ids are collected first so the occurrences changed here can be told
+	// apart from those that already had change.To.
+	cursor, err := es.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = cursor.All(ctx, &matched)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matched) == 0 {
+		return nil, ErrEditConflict
+	}
+
+	ids := make([]primitive.ObjectID, len(matched))
+	for i, m := range matched {
+		ids[i] = m.ID
+	}
+
+	_, err = es.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "status": change.From},
+		bson.M{
+			"$set": bson.M{
+				"status":        change.To,
+				"status_reason": change.Reason,
+				"updated_at":    change.At,
+			},
+			"$push": bson.M{"status_history": change},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// An occurrence another request moved on in the meantime is left out.
+	cursor, err = es.collection.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "status": change.To, "updated_at": change.At},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	err = cursor.All(ctx, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// sendBackForReview returns the fields of a pipeline $set stage that move an
+// approved or published event back to PENDING_REVIEW, recording the change as
+// made by editor. Events in any other status keep it.
+func sendBackForReview(editor string, at time.Time) bson.M {
+	reviewed := bson.M{"$in": bson.A{"$status", reviewedStatuses}}
+
+	change := bson.M{
+		"from":   "$status",
+		"to":     StatusPendingReview,
+		"reason": editedReason,
+		"by":     bson.M{"$literal": editor},
+		"at":     at,
+	}
+
+	return bson.M{
+		"status":        bson.M{"$cond": bson.A{reviewed, StatusPendingReview, "$status"}},
+		"status_reason": bson.M{"$cond": bson.A{reviewed, editedReason, "$status_reason"}},
+		"status_history": bson.M{"$cond": bson.A{
+			reviewed,
+			bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$status_history", bson.A{}}}, bson.A{change}}},
+			"$status_history",
+		}},
+	}
+}
@@ -19,7 +19,7 @@ type Payload struct {
 	Data  map[string]any `json:"data"`
 }
 
-var notifyTopics = []string{"event_add", "event_update", "event_remove", "event_register", "user_registered"}
+var notifyTopics = []string{"event_add", "event_update", "event_remove", "event_register", "event_submitted", "event_approved", "event_rejected", "event_published", "event_cancelled", "event_completed", "event_waitlist_promoted", "user_registered"}
 
 func NewConsumer(conn *amqp.Connection, queueName string) (*Consumer, error) {
 	consumer := &Consumer{
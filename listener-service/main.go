@@ -24,7 +24,7 @@ func main() {
 		panic(err)
 	}
 
-	topics := []string{"event_add", "event_update", "event_remove", "event_register", "user_registered"}
+	topics := []string{"event_add", "event_update", "event_remove", "event_register", "event_submitted", "event_approved", "event_rejected", "event_published", "event_cancelled", "event_completed", "event_waitlist_promoted", "user_registered"}
 	err = consumer.Listen(topics)
 	if err != nil {
 		panic(err)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -104,15 +105,11 @@ func (app *application) extractTokenData(r *http.Request) (string, bool, bool, e
 
 	token = strings.TrimSpace(strings.Replace(token, "Bearer", "", 1))
 
-	claims, err := jwt.HMACCheck([]byte(token), []byte(app.Config.jwt.secret))
+	claims, err := app.verifyToken(token)
 	if err != nil {
 		return "", false, false, err
 	}
 
-	if !claims.Valid(time.Now()) {
-		return "", false, false, errors.New("invalid token")
-	}
-
 	userEmail, ok := claims.Set["email"].(string)
 	if !ok {
 		return "", false, false, errors.New("invalid token")
@@ -130,3 +127,69 @@ func (app *application) extractTokenData(r *http.Request) (string, bool, bool, e
 
 	return userEmail, role, isActivated, nil
 }
+
+// verifyToken checks a JWT locally against the published keys and the
+// denylist, or through the introspection endpoint when it is enabled. API keys
+// have no scope for the mailing lists, so they are refused either way.
+func (app *application) verifyToken(token string) (*jwt.Claims, error) {
+	if app.Introspector != nil {
+		claims, err := app.Introspector.check(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := claims.Set["scopes"]; ok {
+			return nil, errors.New("api keys are not accepted")
+		}
+
+		return claims, nil
+	}
+
+	claims, err := app.Keys.check([]byte(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.Valid(time.Now()) {
+		return nil, errors.New("invalid token")
+	}
+
+	revoked, err := app.isTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// isTokenRevoked asks the authentication service whether the token with the
+// given jti has been logged out before its expiry.
+func (app *application) isTokenRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, errors.New("invalid token")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	response, err := client.Get("http://authentication-service/v1/tokens/denylist/" + url.PathEscape(jti))
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %d from authentication service", response.StatusCode)
+	}
+
+	var body struct {
+		Revoked bool `json:"revoked"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		return false, err
+	}
+
+	return body.Revoked, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+// maxIntrospectionCacheSize bounds the cache so a flood of junk tokens cannot
+// grow it without limit.
+const maxIntrospectionCacheSize = 10_000
+
+// introspection is the authentication service's RFC 7662 response.
+type introspection struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type"`
+	Subject   string   `json:"sub"`
+	Email     string   `json:"email"`
+	Name      string   `json:"name"`
+	Roles     []string `json:"roles"`
+	IsAdmin   bool     `json:"is_admin"`
+	Activated bool     `json:"activated"`
+	Scope     string   `json:"scope"`
+	Expires   int64    `json:"exp"`
+}
+
+// claims turns an active introspection result into claims shaped like those of
+// a JWT, so code reading them need not care how the token was verified.
+func (in *introspection) claims() *jwt.Claims {
+	roles := make([]any, len(in.Roles))
+	for i, role := range in.Roles {
+		roles[i] = role
+	}
+
+	var claims jwt.Claims
+	claims.Subject = in.Subject
+	claims.Expires = jwt.NewNumericTime(time.Unix(in.Expires, 0))
+	claims.Set = map[string]any{
+		"email":       in.Email,
+		"name":        in.Name,
+		"isAdmin":     in.IsAdmin,
+		"isActivated": in.Activated,
+		"roles":       roles,
+	}
+
+	if in.TokenType == "api_key" {
+		scopes := []any{}
+		for _, scope := range strings.Fields(in.Scope) {
+			scopes = append(scopes, scope)
+		}
+		claims.Set["scopes"] = scopes
+	}
+
+	return &claims
+}
+
+type introspectionEntry struct {
+	result    *introspection
+	expiresAt time.Time
+}
+
+// introspector verifies tokens and API keys by asking the authentication
+// service about them instead of checking them locally. Results are cached for
+// up to ttl, which is also how long a revoked token may still be accepted.
+type introspector struct {
+	url    string
+	secret string
+	client *http.Client
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[[32]byte]introspectionEntry
+}
+
+// introspectorFromEnv returns an introspector when TOKEN_INTROSPECTION is true
+// and nil otherwise. TOKEN_INTROSPECTION_SECRET is the secret shared with the
+// authentication service, and TOKEN_INTROSPECTION_CACHE_TTL sets how long
+// results are cached, 30 seconds by default.
+func introspectorFromEnv(url string) *introspector {
+	enabled, _ := strconv.ParseBool(os.Getenv("TOKEN_INTROSPECTION"))
+	if !enabled {
+		return nil
+	}
+
+	secret := os.Getenv("TOKEN_INTROSPECTION_SECRET")
+	if secret == "" {
+		log.Fatal("TOKEN_INTROSPECTION_SECRET must be set when TOKEN_INTROSPECTION is true")
+	}
+
+	ttl := 30 * time.Second
+
+	if s := os.Getenv("TOKEN_INTROSPECTION_CACHE_TTL"); s != "" {
+		var err error
+		ttl, err = time.ParseDuration(s)
+		if err != nil || ttl < 0 {
+			log.Fatalf("Error: Invalid TOKEN_INTROSPECTION_CACHE_TTL value: %s\n", s)
+		}
+	}
+
+	return newIntrospector(url, secret, ttl)
+}
+
+func newIntrospector(url, secret string, ttl time.Duration) *introspector {
+	return &introspector{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 5 * time.Second},
+		ttl:    ttl,
+		cache:  make(map[[32]byte]introspectionEntry),
+	}
+}
+
+// check returns the claims of an active token and an error for anything else.
+func (i *introspector) check(token string) (*jwt.Claims, error) {
+	result, err := i.introspect(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Active {
+		return nil, errors.New("invalid token")
+	}
+
+	return result.claims(), nil
+}
+
+func (i *introspector) introspect(token string) (*introspection, error) {
+	// Only a hash of the token is kept, so the cache holds nothing that could
+	// be replayed.
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	i.mu.Lock()
+	entry, ok := i.cache[key]
+	i.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.result, nil
+	}
+
+	request, err := http.NewRequest(http.MethodPost, i.url, strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Authorization", "Bearer "+i.secret)
+
+	response, err := i.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from authentication service", response.StatusCode)
+	}
+
+	var result introspection
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(i.ttl)
+	if result.Active && result.Expires != 0 && time.Unix(result.Expires, 0).Before(expiresAt) {
+		expiresAt = time.Unix(result.Expires, 0)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.cache) >= maxIntrospectionCacheSize {
+		for k, e := range i.cache {
+			if now.After(e.expiresAt) {
+				delete(i.cache, k)
+			}
+		}
+		if len(i.cache) >= maxIntrospectionCacheSize {
+			i.cache = make(map[[32]byte]introspectionEntry)
+		}
+	}
+
+	i.cache[key] = introspectionEntry{result: &result, expiresAt: expiresAt}
+
+	return &result, nil
+}
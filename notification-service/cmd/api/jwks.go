@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pascaldekloe/jwt"
+)
+
+// jwksCache verifies tokens against the key set published by the
+// authentication service. The set is refetched when it goes stale or when a
+// token names a key ID we have not seen yet, which is what happens right after
+// the authentication service rotates its signing key.
+type jwksCache struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	mu        sync.RWMutex
+	register  *jwt.KeyRegister
+	kids      map[string]bool
+	fetchedAt time.Time
+}
+
+func newJWKSCache(url string) *jwksCache {
+	return &jwksCache{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		ttl:    5 * time.Minute,
+	}
+}
+
+func (c *jwksCache) check(token []byte) (*jwt.Claims, error) {
+	unverified, err := jwt.ParseWithoutCheck(token)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	register := c.register
+	known := c.kids[unverified.KeyID]
+	stale := time.Since(c.fetchedAt) > c.ttl
+	recent := time.Since(c.fetchedAt) < 10*time.Second
+	c.mu.RUnlock()
+
+	if register == nil || stale || (!known && !recent) {
+		register, err = c.fetch()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return register.Check(token)
+}
+
+func (c *jwksCache) fetch() (*jwt.KeyRegister, error) {
+	response, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from authentication service", response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	register := &jwt.KeyRegister{}
+	n, err := register.LoadJWK(body)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.New("authentication service published no signing keys")
+	}
+
+	// Only EdDSA keys are accepted so a token can never be verified with a
+	// weaker algorithm than the one the authentication service signs with.
+	register.ECDSAs, register.RSAs, register.Secrets, register.HMACs = nil, nil, nil, nil
+
+	kids := make(map[string]bool)
+	for _, kid := range register.EdDSAIDs {
+		kids[kid] = true
+	}
+
+	c.mu.Lock()
+	c.register = register
+	c.kids = kids
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return register, nil
+}
@@ -41,9 +41,6 @@ type config struct {
 		password string
 		sender   string
 	}
-	jwt struct {
-		secret string
-	}
 }
 type payload struct {
 	Topic string         `json:"topic"`
@@ -54,6 +51,11 @@ type application struct {
 	Config config
 	Logger *log.Logger
 	Mailer mailer.Mailer
+	Keys   *jwksCache
+
+	// Introspector, when set, verifies tokens through the authentication
+	// service instead of locally.
+	Introspector *introspector
 }
 
 func connectToDb() {
@@ -290,6 +292,10 @@ func (app *application) notify(w http.ResponseWriter, r *http.Request) {
 		app.eventUpdate(Payload)
 	case "event_register":
 		app.eventRegister(Payload)
+	case "event_waitlist_promoted":
+		app.eventWaitlistPromoted(Payload)
+	case "event_submitted", "event_approved", "event_rejected", "event_published", "event_cancelled", "event_completed":
+		app.eventStatus(Payload)
 	default:
 		app.Logger.Println("Unknown Topic")
 		w.WriteHeader(http.StatusBadRequest)
@@ -489,6 +495,95 @@ func (app *application) eventRegister(Payload payload) {
 	})
 }
 
+// eventWaitlistPromoted tells a user on an event's waitlist that a place opened
+// up and they are now attending.
+func (app *application) eventWaitlistPromoted(Payload payload) {
+	emails, err := app.getEmails(Payload)
+	if err != nil {
+		app.Logger.Println("Emails Parse Error")
+		return
+	}
+	eventName, err := app.getEventName(Payload)
+	if err != nil {
+		app.Logger.Println("event Name Parse Error")
+	}
+	eventDate, err := app.getEventDate(Payload)
+	if err != nil {
+		app.Logger.Println("Date Parse Error")
+		return
+	}
+	eventLocation, err := app.getEventLocation(Payload)
+	if err != nil {
+		app.Logger.Println("Location Parse Error")
+		return
+	}
+
+	type promotedStruct struct {
+		Name     string
+		Date     string
+		Location string
+	}
+	data := promotedStruct{
+		Name:     eventName,
+		Date:     eventDate,
+		Location: eventLocation,
+	}
+
+	app.background(func() {
+		err := app.Mailer.Send(emails, "EventWaitlistPromotedTemplate.tmpl", data)
+		if err != nil {
+			app.Logger.Println(err)
+
+		}
+	})
+}
+
+// eventStatus tells an event's organizers that it has moved to a new status,
+// along with the reason an admin or organizer gave for it.
+func (app *application) eventStatus(Payload payload) {
+	emails, err := app.getEmails(Payload)
+	if err != nil {
+		app.Logger.Println("Emails Parse Error")
+		return
+	}
+	eventName, err := app.getEventName(Payload)
+	if err != nil {
+		app.Logger.Println("event Name Parse Error")
+	}
+	eventDate, err := app.getEventDate(Payload)
+	if err != nil {
+		app.Logger.Println("Date Parse Error")
+		return
+	}
+	status, ok := Payload.Data["status"].(string)
+	if !ok {
+		app.Logger.Println("Status Parse Error")
+		return
+	}
+	reason, _ := Payload.Data["reason"].(string)
+
+	type statusStruct struct {
+		Name   string
+		Date   string
+		Status string
+		Reason string
+	}
+	data := statusStruct{
+		Name:   eventName,
+		Date:   eventDate,
+		Status: status,
+		Reason: reason,
+	}
+
+	app.background(func() {
+		err := app.Mailer.Send(emails, "EventStatusTemplate.tmpl", data)
+		if err != nil {
+			app.Logger.Println(err)
+
+		}
+	})
+}
+
 func main() {
 	var cfg config
 	cfg.port = webPort
@@ -498,7 +593,6 @@ func main() {
 	cfg.smtp.username = os.Getenv("MAILHOG_USERNAME")
 	cfg.smtp.password = os.Getenv("MAILHOG_PASSWORD")
 	cfg.smtp.sender = os.Getenv("SENDER_EMAIL")
-	cfg.jwt.secret=os.Getenv("JWT_SECRET")
 
 	if cfg.smtp.host == "" || portStr == "" {
 		log.Fatal("Environment variables for Mailhog are not set")
@@ -506,7 +600,7 @@ func main() {
 	var err error
 	cfg.smtp.port, err = strconv.Atoi(portStr)
 	if err != nil {
-		log.Fatal("Error: Invalid MAILHOG_PORT value: %s\n", portStr)
+		log.Fatalf("Error: Invalid MAILHOG_PORT value: %s\n", portStr)
 		return
 	}
 
@@ -515,6 +609,9 @@ func main() {
 		Config: cfg,
 		Logger: logger,
 		Mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		Keys:   newJWKSCache("http://authentication-service/.well-known/jwks.json"),
+
+		Introspector: introspectorFromEnv("http://authentication-service/v1/tokens/introspect"),
 	}
 
 	log.Printf("starting user service on %s\n", cfg.port)